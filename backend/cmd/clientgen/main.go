@@ -0,0 +1,44 @@
+// Command clientgen generates the operations of pkg/client from the OpenAPI
+// document of the API. It is run by go generate in pkg/client.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/celerix-dev/celerix-flow/internal/openapi"
+)
+
+func main() {
+	spec := flag.String("spec", "", "path to the OpenAPI document")
+	out := flag.String("out", "", "file to write the generated code to")
+	pkg := flag.String("pkg", "client", "package name of the generated code")
+	flag.Parse()
+
+	if *spec == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*spec, *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "clientgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, out, pkg string) error {
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return err
+	}
+	src, err := openapi.GenerateClient(doc, pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/api"
+	"github.com/celerix-dev/celerix-flow/internal/backup"
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// closeStore blocks until the embedded store has written its current state
+// to disk, so the process does not exit with changes still pending, and
+// closes the connection of a remote store.
+func closeStore(store sdk.CelerixStore) {
+	if err := db.Flush(store); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to flush store: %v\n", err)
+	}
+	if c, ok := store.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close store: %v\n", err)
+		}
+	}
+}
+
+func appVersion() string {
+	var vCfg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(versionFile, &vCfg); err != nil || vCfg.Version == "" {
+		return "unknown"
+	}
+	return vCfg.Version
+}
+
+// resolveClient finds a client by ID or, failing that, by recovery code.
+func resolveClient(store sdk.CelerixStore, ref string) (*db.ClientRecord, error) {
+	if client, err := db.GetClient(store, ref); err == nil {
+		return client, nil
+	}
+	client, err := db.GetClientByRecoveryCode(store, ref)
+	if err != nil {
+		return nil, fmt.Errorf("no client with ID or recovery code %q", ref)
+	}
+	return client, nil
+}
+
+func runAdmin(store sdk.CelerixStore, args []string) int {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		fmt.Fprint(os.Stderr, "usage: flow admin grant|revoke <client>\n")
+		return 2
+	}
+
+	client, err := resolveClient(store, args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	grant := args[0] == "grant"
+	if err := db.UpdateClientAdminStatus(store, client.ID, grant); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to update admin status: %v\n", err)
+		return 1
+	}
+
+	if grant {
+		fmt.Printf("%s (%s) is now an admin\n", client.Name, client.ID)
+	} else {
+		fmt.Printf("%s (%s) is no longer an admin\n", client.Name, client.ID)
+	}
+	return 0
+}
+
+func runClient(store sdk.CelerixStore, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "usage: flow client list|rename|delete ...\n")
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		return runClientList(store)
+	case "rename":
+		return runClientRename(store, args[1:])
+	case "delete":
+		return runClientDelete(store, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown client command %q\n", args[0])
+		return 2
+	}
+}
+
+func runClientList(store sdk.CelerixStore) int {
+	clients, err := db.ListClients(store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list clients: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tRECOVERY CODE\tADMIN\tLAST ACTIVE")
+	for _, c := range clients {
+		lastActive := "-"
+		if c.LastActive > 0 {
+			lastActive = time.Unix(c.LastActive, 0).Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\n", c.ID, c.Name, c.RecoveryCode, c.IsAdmin, lastActive)
+	}
+	tw.Flush()
+	return 0
+}
+
+func runClientRename(store sdk.CelerixStore, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, "usage: flow client rename <client> <name>\n")
+		return 2
+	}
+
+	client, err := resolveClient(store, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := db.UpdateClientFull(store, client.ID, args[1], client.RecoveryCode, client.IsAdmin); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to rename client: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("renamed %s to %s\n", client.Name, args[1])
+	return 0
+}
+
+func runClientDelete(store sdk.CelerixStore, args []string) int {
+	fs := flag.NewFlagSet("client delete", flag.ExitOnError)
+	mode := fs.String("mode", "cascade", "cascade (trash all data) or transfer (hand data to -target)")
+	target := fs.String("target", "", "client receiving the data when -mode=transfer")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "usage: flow client delete [-mode cascade|transfer] [-target <client>] <client>\n")
+		return 2
+	}
+
+	client, err := resolveClient(store, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	now := time.Now()
+	targetID := ""
+	switch *mode {
+	case "cascade":
+		err = db.TrashClientCascade(store, client.ID, now.Unix())
+	case "transfer":
+		heir, errTarget := resolveClient(store, *target)
+		if errTarget != nil {
+			fmt.Fprintln(os.Stderr, errTarget)
+			return 1
+		}
+		targetID = heir.ID
+		err = db.TrashClientWithTransfer(store, client.ID, heir.ID, now.Unix())
+	default:
+		fmt.Fprintf(os.Stderr, "mode must be cascade or transfer, got %q\n", *mode)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to delete client: %v\n", err)
+		return 1
+	}
+	if err := api.QueueClientDeleted(store, client, *mode, targetID, now); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to queue the client.deleted webhook event: %v\n", err)
+	}
+
+	fmt.Printf("moved %s (%s) to the trash\n", client.Name, client.ID)
+	return 0
+}
+
+func runExport(store sdk.CelerixStore, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "-", "output file, - for stdout")
+	fs.Parse(args)
+
+	// A file is written next to its final path and only renamed into place
+	// once complete, so a failed export leaves no truncated archive behind.
+	var w io.Writer = os.Stdout
+	var tmp *os.File
+	if *out != "-" {
+		var err error
+		tmp, err = os.CreateTemp(filepath.Dir(*out), "."+filepath.Base(*out)+".tmp-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create %s: %v\n", *out, err)
+			return 1
+		}
+		defer os.Remove(tmp.Name())
+		w = tmp
+	}
+
+	manifest, err := backup.Export(w, store, appVersion())
+	if tmp != nil {
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), *out)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write backup: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d keys and %d blobs\n", manifest.Keys, len(manifest.Blobs))
+	return 0
+}
+
+func runImport(store sdk.CelerixStore, storageDir string, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "usage: flow import <file>\n")
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	defer f.Close()
+
+	manifest, err := backup.Restore(f, store, storageDir, appVersion())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to restore backup: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("restored %d keys and %d blobs from a %s backup made %s\n",
+		manifest.Keys, len(manifest.Blobs), manifest.AppVersion,
+		time.Unix(manifest.CreatedAt, 0).Format(time.RFC3339))
+	return 0
+}
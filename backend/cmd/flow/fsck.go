@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/celerix-dev/celerix-flow/internal/fsck"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// runFsck implements `flow fsck [-repair] [-json]`. It returns the process
+// exit code: 0 when the data is consistent (or fully repaired), 1 otherwise.
+func runFsck(store sdk.CelerixStore, storageDir string, args []string) int {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "fix the issues that are found")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	report, err := fsck.Run(store, storageDir, *repair)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fsck failed: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, issue := range report.Issues {
+			status := ""
+			if issue.Repaired {
+				status = " (repaired)"
+			}
+			fmt.Printf("%-16s %s: %s%s\n", issue.Kind, issue.Path, issue.Detail, status)
+		}
+		fmt.Printf("checked %d records and %d blobs, %d issue(s), %d unresolved\n",
+			report.Records, report.Blobs, len(report.Issues), report.Unresolved())
+	}
+
+	if report.Unresolved() > 0 {
+		return 1
+	}
+	return 0
+}
@@ -2,20 +2,15 @@ package main
 
 import (
 	"embed"
-	"io/fs"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/celerix-dev/celerix-flow/internal/api"
+	"github.com/celerix-dev/celerix-flow/internal/config"
+	"github.com/celerix-dev/celerix-flow/internal/logging"
 	_ "github.com/celerix-dev/celerix-store/pkg/engine"
 	"github.com/celerix-dev/celerix-store/pkg/sdk"
-	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 //go:embed all:dist
@@ -24,144 +19,102 @@ var frontendDist embed.FS
 //go:embed version.json
 var versionFile []byte
 
-func main() {
-	dataDir := os.Getenv("DATA_DIR")
-	if dataDir == "" {
-		dataDir = "./data"
-	}
+const usage = `Usage: flow [-config file] [-data-dir dir] [-storage-dir dir] <command> [arguments]
+
+Commands:
+  serve [-port n] [-env dev|production] start the server (default)
+  admin grant <client>                  give a client admin rights
+  admin revoke <client>                 take admin rights away from a client
+  client list                           list all clients
+  client rename <client> <name>         rename a client
+  client delete [-mode cascade|transfer] [-target <client>] <client>
+                                        move a client to the trash
+  export [-o file]                      write a tar.gz backup of data and uploads
+  import <file>                         replace all data with a backup
+  fsck [-repair] [-json]                check files against their records
+  version                               print the version
+
+Settings are read from the config file (-config or FLOW_CONFIG), then from
+the environment (DATA_DIR, STORAGE_DIR, CELERIX_NAMESPACE, ADMIN_SECRET, PORT,
+ENV, TRASH_RETENTION), then from flags.
+
+A <client> is given by ID or recovery code. Commands other than serve work
+directly on the data and storage directories; stop the server first unless
+the store is remote (CELERIX_STORE_ADDR).
+`
 
-	storageDir := os.Getenv("STORAGE_DIR")
-	if storageDir == "" {
-		storageDir = filepath.Join(dataDir, "uploads")
+func main() {
+	global := flag.NewFlagSet("flow", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := global.String("config", os.Getenv(config.EnvFile), "path to a YAML config file")
+	dataDir := global.String("data-dir", "", "override data_dir")
+	storageDir := global.String("storage-dir", "", "override storage_dir")
+	global.Parse(os.Args[1:])
+
+	cmd := "serve"
+	args := global.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
 	}
 
-	// Ensure directories exist
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
-	}
-	if err := os.MkdirAll(storageDir, 0755); err != nil {
-		log.Fatalf("Failed to create storage directory: %v", err)
+	switch cmd {
+	case "version":
+		fmt.Println(appVersion())
+		return
+	case "help":
+		fmt.Print(usage)
+		return
 	}
 
-	namespaceStr := os.Getenv("CELERIX_NAMESPACE")
-	if namespaceStr == "" {
-		log.Fatal("CELERIX_NAMESPACE environment variable is required")
-	}
-	celerixNamespace, err := uuid.Parse(namespaceStr)
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatalf("Failed to parse CELERIX_NAMESPACE as UUID: %v", err)
+		log.Fatalf("Invalid configuration: %v", err)
 	}
-
-	store, err := sdk.New(dataDir)
-	if err != nil {
-		log.Fatalf("Failed to initialize Celerix Store: %v", err)
+	if *dataDir != "" {
+		cfg.DataDir = *dataDir
 	}
-
-	h := &api.Handler{
-		Store:            store,
-		StorageDir:       storageDir,
-		AdminSecret:      os.Getenv("ADMIN_SECRET"),
-		VersionConfig:    versionFile,
-		CelerixNamespace: celerixNamespace,
+	if *storageDir != "" {
+		cfg.StorageDir = *storageDir
 	}
 
-	// Set Gin mode based on the environment
-	if os.Getenv("ENV") != "dev" {
-		gin.SetMode(gin.ReleaseMode)
+	// serve has flags of its own that override the configuration
+	if cmd == "serve" {
+		args = parseServeFlags(cfg, args)
 	}
 
-	r := gin.Default()
-
-	// CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Client-ID, X-Admin-Secret")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	apiGroup := r.Group("/api")
-	{
-		apiGroup.GET("/version", h.GetVersion)
-		apiGroup.GET("/persona", h.GetPersona)
-		apiGroup.POST("/persona/name", h.UpdateClientName)
-		apiGroup.POST("/persona/recover", h.RecoverPersona)
-		apiGroup.POST("/persona/admin", h.ActivateAdmin)
-
-		// Kanban endpoints
-		apiGroup.GET("/kanban", h.GetKanban)
-		apiGroup.POST("/kanban", h.SaveKanban)
-
-		// Generic endpoints for key-value storage
-		apiGroup.GET("/store/:key", h.GetGeneric)
-		apiGroup.POST("/store/:key", h.SaveGeneric)
-
-		apiGroup.POST("/upload", h.UploadFile)
-		apiGroup.GET("/files", h.ListFiles)
-		apiGroup.GET("/files/:id", h.GetFileMetadata)
-		apiGroup.PUT("/files/:id", h.UpdateFile)
-		apiGroup.DELETE("/files/:id", h.DeleteFile)
-		apiGroup.GET("/clients", h.ListClients)
-		apiGroup.PUT("/clients/:id", h.UpdateClient)
-		apiGroup.DELETE("/clients/:id", h.DeleteClient)
-		apiGroup.GET("/download/:id", h.DownloadFile)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
+	logging.Setup(os.Stderr, cfg.Level, cfg.LogFormat)
 
-	// Serve frontend static files
-	distFS, err := fs.Sub(frontendDist, "dist")
-	if err != nil {
-		log.Fatalf("Failed to sub embedded dist: %v", err)
+	if err := cfg.EnsureDirs(); err != nil {
+		log.Fatal(err)
 	}
 
-	if os.Getenv("ENV") == "dev" {
-		// PROXY MODE: Use a custom handler for anything not matched by Gin routes
-		target, _ := url.Parse("http://localhost:5173")
-		proxy := httputil.NewSingleHostReverseProxy(target)
-
-		r.NoRoute(func(c *gin.Context) {
-			proxy.ServeHTTP(c.Writer, c.Request)
-		})
-	} else {
-
-		r.NoRoute(func(c *gin.Context) {
-			path := c.Request.URL.Path
-			// If it's an API request that reached here, return 404 as JSON
-			if strings.HasPrefix(path, "/api") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "API route not found"})
-				return
-			}
-
-			// Try to serve the file from the embedded filesystem
-			file, err := distFS.Open(strings.TrimPrefix(path, "/"))
-			if err == nil {
-				err := file.Close()
-				if err != nil {
-					return
-				}
-				http.FileServer(http.FS(distFS)).ServeHTTP(c.Writer, c.Request)
-				return
-			}
-
-			// Fallback to index.html for SPA routing
-			c.FileFromFS("/", http.FS(distFS))
-		})
-
+	store, err := sdk.New(cfg.DataDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize Celerix Store: %v", err)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	var code int
+	switch cmd {
+	case "serve":
+		code = runServe(cfg, store)
+	case "admin":
+		code = runAdmin(store, args)
+	case "client":
+		code = runClient(store, args)
+	case "export":
+		code = runExport(store, args)
+	case "import":
+		code = runImport(store, cfg.StorageDir, args)
+	case "fsck":
+		code = runFsck(store, cfg.StorageDir, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		code = 2
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	closeStore(store)
+	os.Exit(code)
 }
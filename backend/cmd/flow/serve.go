@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/api"
+	"github.com/celerix-dev/celerix-flow/internal/certs"
+	"github.com/celerix-dev/celerix-flow/internal/config"
+	"github.com/celerix-dev/celerix-flow/internal/cors"
+	"github.com/celerix-dev/celerix-flow/internal/logging"
+	"github.com/celerix-dev/celerix-flow/internal/metrics"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/gin-gonic/gin"
+)
+
+// parseServeFlags applies the flags of the serve command to cfg and returns
+// the remaining arguments.
+func parseServeFlags(cfg *config.Config, args []string) []string {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.IntVar(&cfg.Port, "port", cfg.Port, "port to listen on")
+	flags.StringVar(&cfg.Env, "env", cfg.Env, "dev or production")
+	flags.Parse(args)
+	return flags.Args()
+}
+
+// runServe runs the HTTP server until SIGINT or SIGTERM. It is the default
+// command. On a signal it stops accepting connections, lets in-flight
+// requests finish for up to ShutdownTimeout and stops background work; the
+// caller flushes and closes the store afterwards.
+func runServe(cfg *config.Config, store sdk.CelerixStore) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	h := api.NewHandler(cfg, metrics.InstrumentStore(store), versionFile)
+	h.RegisterMetrics(metrics.Default)
+	if err := h.Index.Rebuild(h.Store); err != nil {
+		slog.Error("failed to build search index", "err", err)
+		return 1
+	}
+	slog.Info("search index built", "documents", h.Index.Len())
+
+	// Permanently remove trashed files and clients once retention expires
+	var background sync.WaitGroup
+	background.Go(func() { h.RunTrashPurger(ctx, time.Hour) })
+	// Record and push reminders about cards due soon or overdue
+	background.Go(func() { h.RunDueDateReminders(ctx, cfg.ReminderInterval) })
+	// Post board, file and client events to webhook subscribers
+	background.Go(func() { h.RunWebhooks(ctx, cfg.WebhookInterval) })
+	// Send the notification emails clients opted into
+	if h.Mailer != nil {
+		background.Go(func() { h.RunOutbox(ctx, cfg.OutboxInterval) })
+	}
+
+	// Set Gin mode based on the environment
+	if cfg.Env != "dev" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r := gin.New()
+	r.Use(logging.Middleware(), gin.CustomRecovery(api.Recovered))
+
+	if cfg.TLSEnabled() && cfg.HSTSMaxAge > 0 {
+		r.Use(hsts(cfg))
+	}
+	r.Use(metrics.Middleware())
+	r.Use(cors.Middleware(cfg.CORS))
+
+	// Probes and metrics for orchestrators and monitoring
+	r.GET("/healthz", h.Healthz)
+	r.GET("/readyz", h.Readyz)
+	r.GET("/metrics", metrics.Handler)
+
+	h.Mount(r)
+
+	// Serve frontend static files
+	distFS, err := fs.Sub(frontendDist, "dist")
+	if err != nil {
+		slog.Error("failed to sub embedded dist", "err", err)
+		return 1
+	}
+
+	if cfg.Env == "dev" {
+		// PROXY MODE: Use a custom handler for anything not matched by Gin routes
+		target, _ := url.Parse(cfg.DevServerURL)
+		proxy := httputil.NewSingleHostReverseProxy(target)
+
+		r.NoRoute(func(c *gin.Context) {
+			proxy.ServeHTTP(c.Writer, c.Request)
+		})
+	} else {
+
+		r.NoRoute(func(c *gin.Context) {
+			path := c.Request.URL.Path
+			// If it's an API request that reached here, return 404 as JSON
+			if strings.HasPrefix(path, api.Prefix) {
+				api.RouteNotFound(c)
+				return
+			}
+
+			// Try to serve the file from the embedded filesystem
+			file, err := distFS.Open(strings.TrimPrefix(path, "/"))
+			if err == nil {
+				err := file.Close()
+				if err != nil {
+					return
+				}
+				http.FileServer(http.FS(distFS)).ServeHTTP(c.Writer, c.Request)
+				return
+			}
+
+			// Fallback to index.html for SPA routing
+			c.FileFromFS("/", http.FS(distFS))
+		})
+
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	// Event streams never finish on their own; end them on shutdown
+	srv.RegisterOnShutdown(h.Events.Close)
+	servers := []*http.Server{srv}
+	serveErr := make(chan error, 2)
+
+	if cfg.TLSEnabled() {
+		reloader, err := certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			slog.Error("failed to load TLS certificate", "err", err)
+			return 1
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+		background.Go(func() { reloader.Watch(ctx, time.Minute) })
+
+		go func() {
+			slog.Info("server starting", "port", cfg.Port, "tls", true)
+			serveErr <- srv.ListenAndServeTLS("", "")
+		}()
+
+		if cfg.RedirectPort != 0 {
+			redirect := &http.Server{
+				Addr:              ":" + strconv.Itoa(cfg.RedirectPort),
+				Handler:           redirectToHTTPS(cfg.Port),
+				ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+				IdleTimeout:       cfg.IdleTimeout,
+			}
+			servers = append(servers, redirect)
+			go func() {
+				slog.Info("redirecting HTTP to HTTPS", "port", cfg.RedirectPort)
+				serveErr <- redirect.ListenAndServe()
+			}()
+		}
+	} else {
+		go func() {
+			slog.Info("server starting", "port", cfg.Port, "tls", false)
+			serveErr <- srv.ListenAndServe()
+		}()
+	}
+
+	code := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("failed to start server", "err", err)
+		code = 1
+	case <-ctx.Done():
+		slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			slog.Error("graceful shutdown incomplete, closing remaining connections", "err", err)
+			s.Close()
+			code = 1
+		}
+	}
+
+	background.Wait()
+	slog.Info("server stopped")
+	return code
+}
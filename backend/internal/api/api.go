@@ -2,14 +2,22 @@ package api
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/celerix-dev/celerix-flow/internal/config"
 	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/events"
+	"github.com/celerix-dev/celerix-flow/internal/logging"
+	"github.com/celerix-dev/celerix-flow/internal/mail"
+	"github.com/celerix-dev/celerix-flow/internal/metrics"
+	"github.com/celerix-dev/celerix-flow/internal/search"
 	"github.com/celerix-dev/celerix-flow/internal/storage"
+	"github.com/celerix-dev/celerix-flow/internal/webhook"
 	"github.com/celerix-dev/celerix-store/pkg/sdk"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -23,15 +31,77 @@ type Handler struct {
 	AdminSecret      string
 	VersionConfig    []byte
 	CelerixNamespace uuid.UUID
+	TrashRetention   time.Duration
+	// AliasSunset retires the unversioned /api alias; zero keeps it.
+	AliasSunset time.Time
+	// Index serves /search; Store must keep it up to date.
+	Index *search.Index
+	// Events carries notifications to the clients' /events streams.
+	Events *events.Bus
+	// DueSoon is how long before their due date cards are reported as due
+	// soon; zero means DefaultDueSoon.
+	DueSoon time.Duration
+	// Mailer sends the queued notification emails; nil disables email.
+	Mailer mail.Sender
+	// Webhooks posts queued webhook deliveries; nil leaves them queued.
+	Webhooks *webhook.Sender
+}
+
+// NewHandler wires a handler from a validated configuration. Writes through
+// the handler's store update its search index, which starts out empty; see
+// search.Index.Rebuild. Emails are only sent when SMTP is configured.
+func NewHandler(cfg *config.Config, store CelerixStore, versionConfig []byte) *Handler {
+	index := search.NewIndex()
+	h := &Handler{
+		Store:            search.Watch(store, index),
+		Index:            index,
+		StorageDir:       cfg.StorageDir,
+		AdminSecret:      cfg.AdminSecret,
+		VersionConfig:    versionConfig,
+		CelerixNamespace: cfg.CelerixNamespace,
+		TrashRetention:   cfg.TrashRetention,
+		AliasSunset:      cfg.AliasSunset,
+		Events:           events.NewBus(),
+		DueSoon:          cfg.DueSoon,
+	}
+	h.Webhooks = &webhook.Sender{UserAgent: "celerix-flow/" + h.appVersion()}
+	if cfg.MailEnabled() {
+		h.Mailer = &mail.SMTP{
+			Addr:     cfg.SMTPAddr(),
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			TLS:      cfg.SMTPTLS,
+		}
+	}
+	return h
 }
 
 func (h *Handler) GetVersion(c *gin.Context) {
 	c.Data(http.StatusOK, "application/json", h.VersionConfig)
 }
 
+// appVersion extracts the version from the VersionConfig bytes.
+func (h *Handler) appVersion() string {
+	var vCfg struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(h.VersionConfig, &vCfg); err != nil {
+		return "unknown"
+	}
+	return vCfg.Version
+}
+
+// logger returns the logger of the request, tagged with its request ID.
+func logger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// isAdmin reports whether the caller is an admin. API tokens never carry
+// admin rights, even those of admins.
 func (h *Handler) isAdmin(c *gin.Context) bool {
 	ownerID := c.GetHeader("X-Client-ID")
-	if ownerID == "" {
+	if ownerID == "" || viaToken(c) {
 		return false
 	}
 	client, err := db.GetClient(h.Store, ownerID)
@@ -63,27 +133,18 @@ func (h *Handler) GetPersona(c *gin.Context) {
 		persona = "admin"
 	}
 
-	// Extract version from VersionConfig bytes
-	version := "unknown"
-	var vCfg struct {
-		Version string `json:"version"`
-	}
-	if err := json.Unmarshal(h.VersionConfig, &vCfg); err == nil {
-		version = vCfg.Version
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"persona":       persona,
 		"name":          name,
 		"recovery_code": recoveryCode,
-		"version":       version,
+		"version":       h.appVersion(),
 	})
 }
 
 func (h *Handler) ActivateAdmin(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
@@ -91,19 +152,19 @@ func (h *Handler) ActivateAdmin(c *gin.Context) {
 		Secret string `json:"secret" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
 	if h.AdminSecret == "" || input.Secret != h.AdminSecret {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid admin secret"})
+		forbidden(c, "Invalid admin secret")
 		return
 	}
 
 	// Flag the current client as admin in DB
 	err := db.UpdateClientAdminStatus(h.Store, ownerID, true)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate admin status"})
+		internalError(c, "Failed to activate admin status", err)
 		return
 	}
 
@@ -113,17 +174,17 @@ func (h *Handler) ActivateAdmin(c *gin.Context) {
 func (h *Handler) GetKanban(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
-	val, err := h.Store.Get(ownerID, "flow", "kanban")
+	val, err := db.GetValue(h.Store, ownerID, db.KanbanKey)
+	if errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusOK, gin.H{"columns": []interface{}{}})
+		return
+	}
 	if err != nil {
-		if err.Error() == "key not found" || err.Error() == "app not found" || err.Error() == "persona not found" {
-			c.JSON(http.StatusOK, gin.H{"columns": []interface{}{}})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "Failed to load kanban", err)
 		return
 	}
 
@@ -133,24 +194,32 @@ func (h *Handler) GetKanban(c *gin.Context) {
 func (h *Handler) SaveKanban(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
 	var input interface{}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
-	// In a real scenario, we might want to validate the structure here
-	// but for a 1:1 copy we trust the frontend for now.
+	h.saveKanban(c, ownerID, input)
+}
 
-	err := h.Store.Set(ownerID, "flow", "kanban", input)
+// saveKanban stores a board once its project and assignee references check
+// out, and tells assignees and webhooks what changed.
+func (h *Handler) saveKanban(c *gin.Context, ownerID string, input any) {
+	board, err := db.DecodeKanban(input)
+	var changes db.KanbanChanges
+	if err == nil {
+		changes, err = db.SaveKanban(h.Store, ownerID, board)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, "Failed to save kanban")
 		return
 	}
+	h.kanbanChanged(c, ownerID, changes)
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
@@ -158,23 +227,23 @@ func (h *Handler) SaveKanban(c *gin.Context) {
 func (h *Handler) GetGeneric(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
 	key := c.Param("key")
 	if key == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		badRequest(c, "key is required")
 		return
 	}
 
-	val, err := h.Store.Get(ownerID, "flow", key)
+	val, err := db.GetValue(h.Store, ownerID, key)
+	if errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusOK, nil)
+		return
+	}
 	if err != nil {
-		if err.Error() == "key not found" || err.Error() == "app not found" || err.Error() == "persona not found" {
-			c.JSON(http.StatusOK, nil)
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "Failed to load data", err)
 		return
 	}
 
@@ -184,25 +253,39 @@ func (h *Handler) GetGeneric(c *gin.Context) {
 func (h *Handler) SaveGeneric(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
 	key := c.Param("key")
 	if key == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		badRequest(c, "key is required")
+		return
+	}
+
+	if db.ServerManaged(key) {
+		respondError(c, db.Validation("Key %s is managed by the server", key), "Failed to save data")
 		return
 	}
 
 	var input interface{}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
+		return
+	}
+
+	switch key {
+	case db.ProjectsKey:
+		h.saveProjectsBlob(c, ownerID, input)
+		return
+	case db.KanbanKey:
+		h.saveKanban(c, ownerID, input)
 		return
 	}
 
-	err := h.Store.Set(ownerID, "flow", key, input)
+	err := h.Store.Set(ownerID, db.AppID, key, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "Failed to save data", err)
 		return
 	}
 
@@ -214,14 +297,18 @@ func (h *Handler) RecoverPersona(c *gin.Context) {
 		Code string `json:"code" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
 	// Otherwise, check client recovery codes
 	client, err := db.GetClientByRecoveryCode(h.Store, input.Code)
+	if errors.Is(err, db.ErrNotFound) {
+		notFound(c, "Invalid recovery code")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid recovery code"})
+		internalError(c, "Failed to recover persona", err)
 		return
 	}
 
@@ -243,7 +330,7 @@ func (h *Handler) RecoverPersona(c *gin.Context) {
 func (h *Handler) UpdateClientName(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
@@ -251,7 +338,7 @@ func (h *Handler) UpdateClientName(c *gin.Context) {
 		Name string `json:"name" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
@@ -270,8 +357,7 @@ func (h *Handler) UpdateClientName(c *gin.Context) {
 
 	err = db.UpsertClient(h.Store, deterministicID, input.Name, recoveryCode, time.Now().Unix())
 	if err != nil {
-		log.Printf("[ERROR] Failed to upsert client: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update client name"})
+		internalError(c, "Failed to update client name", err)
 		return
 	}
 
@@ -285,14 +371,14 @@ func (h *Handler) UpdateClientName(c *gin.Context) {
 func (h *Handler) UploadFile(c *gin.Context) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		badRequest(c, "No file is received")
 		return
 	}
 	defer file.Close()
 
 	ownerID := c.GetHeader("X-Client-ID")
 	if ownerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+		badRequest(c, "X-Client-ID header is required")
 		return
 	}
 
@@ -301,9 +387,10 @@ func (h *Handler) UploadFile(c *gin.Context) {
 
 	storedPath, size, err := storage.StoreFile(file, h.StorageDir, storedName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file: " + err.Error()})
+		internalError(c, "Failed to store file", err)
 		return
 	}
+	metrics.UploadBytes.Add(float64(size))
 
 	// Generate public download link
 	downloadLink := uuid.New().String()
@@ -324,13 +411,18 @@ func (h *Handler) UploadFile(c *gin.Context) {
 		IsPublic:     isPublic,
 	}
 
-	log.Printf("[DEBUG] Saving record: ID=%s, Name=%s, OwnerID=%s", record.ID, record.OriginalName, record.OwnerID)
+	logger(c).Debug("saving file record", "file_id", record.ID, "owner_id", record.OwnerID, "size", record.Size)
 	err = db.SaveFileRecord(h.Store, record)
 	if err != nil {
-		log.Printf("[DEBUG] Failed to save record: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save record: " + err.Error()})
+		internalError(c, "Failed to save file record", err)
 		return
 	}
+	// Subscribers have no use for where the blob lives on disk, and the
+	// event names the owner by handle only
+	published := record
+	published.StoredPath = ""
+	published.OwnerID = ""
+	h.emit(c, db.EventFileUploaded, ownerID, published)
 
 	c.JSON(http.StatusOK, record)
 }
@@ -360,22 +452,21 @@ func (h *Handler) ListFiles(c *gin.Context) {
 
 	if !isAdmin {
 		if ownerID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "X-Client-ID header is required"})
+			badRequest(c, "X-Client-ID header is required")
 			return
 		}
 		opts.OwnerID = ownerID
 	}
 
-	log.Printf("[DEBUG] ListFiles request: isAdmin=%v, X-Client-ID=%s, Search=%s, Page=%d, Limit=%d", isAdmin, ownerID, search, page, limit)
+	logger(c).Debug("listing files", "admin", isAdmin, "client_id", ownerID, "search", search != "", "page", page, "limit", limit)
 
 	response, err := db.ListFiles(h.Store, opts)
 	if err != nil {
-		log.Printf("[DEBUG] Failed to list files: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list files"})
+		internalError(c, "Failed to list files", err)
 		return
 	}
 
-	log.Printf("[DEBUG] Returning %d records (Total: %d)", len(response.Files), response.Total)
+	logger(c).Debug("listed files", "returned", len(response.Files), "total", response.Total)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -383,6 +474,7 @@ func (h *Handler) DownloadFile(c *gin.Context) {
 	idOrLink := c.Param("id")
 	// Try finding by ID first
 	record, err := db.GetFileRecord(h.Store, idOrLink)
+	viaLink := false
 	if err != nil {
 		// Try finding by download_link
 		// In Celerix Store, we'll list all and filter for now
@@ -392,17 +484,31 @@ func (h *Handler) DownloadFile(c *gin.Context) {
 				if r.DownloadLink == idOrLink {
 					record = &r
 					err = nil
+					viaLink = true
 					break
 				}
 			}
 		}
 
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			notFound(c, "File not found")
 			return
 		}
 	}
 
+	// Owners hear about others downloading through the share link
+	if viaLink && record.OwnerID != "" && record.OwnerID != c.GetHeader("X-Client-ID") {
+		n := db.Notification{
+			Type:   db.NotificationDownloaded,
+			Owner:  record.OwnerID,
+			FileID: record.ID,
+			Title:  record.OriginalName,
+		}
+		if _, err := h.notify(record.OwnerID, []db.Notification{n}, time.Now()); err != nil {
+			logger(c).Error("failed to notify file owner", "file", record.ID, "err", err)
+		}
+	}
+
 	c.FileAttachment(record.StoredPath, record.OriginalName)
 }
 
@@ -410,7 +516,7 @@ func (h *Handler) GetFileMetadata(c *gin.Context) {
 	id := c.Param("id")
 	record, err := db.GetFileRecord(h.Store, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		respondError(c, err, "Failed to load file")
 		return
 	}
 
@@ -421,7 +527,7 @@ func (h *Handler) UpdateFile(c *gin.Context) {
 	id := c.Param("id")
 	record, err := db.GetFileRecord(h.Store, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		respondError(c, err, "Failed to load file")
 		return
 	}
 
@@ -429,7 +535,7 @@ func (h *Handler) UpdateFile(c *gin.Context) {
 	ownerID := c.GetHeader("X-Client-ID")
 	isAdmin := h.isAdmin(c)
 	if !isAdmin && record.OwnerID != ownerID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this file"})
+		forbidden(c, "You don't have permission to update this file")
 		return
 	}
 
@@ -440,7 +546,7 @@ func (h *Handler) UpdateFile(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
@@ -452,7 +558,7 @@ func (h *Handler) UpdateFile(c *gin.Context) {
 
 	err = db.UpdateFileRecord(h.Store, id, input.OriginalName, finalOwnerID, input.IsPublic)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update file"})
+		internalError(c, "Failed to update file", err)
 		return
 	}
 
@@ -463,28 +569,21 @@ func (h *Handler) DeleteFile(c *gin.Context) {
 	id := c.Param("id")
 	record, err := db.GetFileRecord(h.Store, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		respondError(c, err, "Failed to load file")
 		return
 	}
 
 	// Permission check: admin or owner
 	ownerID := c.GetHeader("X-Client-ID")
 	if !h.isAdmin(c) && record.OwnerID != ownerID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this file"})
+		forbidden(c, "You don't have permission to delete this file")
 		return
 	}
 
-	// Delete from storage
-	err = storage.DeleteFile(record.StoredPath)
-	if err != nil {
-		log.Printf("[ERROR] Failed to delete file from storage: %v", err)
-		// We continue even if file is missing from storage to clean up DB
-	}
-
-	// Delete from DB
-	err = db.DeleteFileRecord(h.Store, id)
+	// Move to trash; the blob is removed by the purger once retention expires
+	err = db.TrashFileRecord(h.Store, id, time.Now().Unix())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file record"})
+		internalError(c, "Failed to delete file record", err)
 		return
 	}
 
@@ -493,13 +592,13 @@ func (h *Handler) DeleteFile(c *gin.Context) {
 
 func (h *Handler) ListClients(c *gin.Context) {
 	if !h.isAdmin(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		forbidden(c, "Admin access required")
 		return
 	}
 
 	clients, err := db.ListClients(h.Store)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list clients"})
+		internalError(c, "Failed to list clients", err)
 		return
 	}
 
@@ -508,7 +607,7 @@ func (h *Handler) ListClients(c *gin.Context) {
 
 func (h *Handler) UpdateClient(c *gin.Context) {
 	if !h.isAdmin(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		forbidden(c, "Admin access required")
 		return
 	}
 
@@ -520,20 +619,20 @@ func (h *Handler) UpdateClient(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(c, err.Error())
 		return
 	}
 
 	// Protection: Admin cannot remove the flag from itself
 	currentAdminID := c.GetHeader("X-Client-ID")
 	if id == currentAdminID && !input.IsAdmin {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove admin status from yourself"})
+		badRequest(c, "Cannot remove admin status from yourself")
 		return
 	}
 
 	err := db.UpdateClientFull(h.Store, id, input.Name, input.RecoveryCode, input.IsAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update client"})
+		internalError(c, "Failed to update client", err)
 		return
 	}
 
@@ -542,7 +641,7 @@ func (h *Handler) UpdateClient(c *gin.Context) {
 
 func (h *Handler) DeleteClient(c *gin.Context) {
 	if !h.isAdmin(c) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		forbidden(c, "Admin access required")
 		return
 	}
 
@@ -551,15 +650,47 @@ func (h *Handler) DeleteClient(c *gin.Context) {
 	// Protection: Admin cannot delete themselves
 	currentAdminID := c.GetHeader("X-Client-ID")
 	if id == currentAdminID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete yourself"})
+		badRequest(c, "Cannot delete yourself")
 		return
 	}
 
-	err := db.DeleteClient(h.Store, id)
+	client, err := db.GetClient(h.Store, id)
+	if err != nil {
+		respondError(c, err, "Failed to load client")
+		return
+	}
+
+	// mode=cascade (default) trashes the client with all of their data,
+	// mode=transfer hands their data over to the client given in target.
+	mode, targetID := c.DefaultQuery("mode", "cascade"), ""
+	switch mode {
+	case "cascade":
+		err = db.TrashClientCascade(h.Store, id, time.Now().Unix())
+	case "transfer":
+		targetID = c.Query("target")
+		if targetID == "" || targetID == id {
+			badRequest(c, "A different target client is required for transfer")
+			return
+		}
+		if _, errTarget := db.GetClient(h.Store, targetID); errors.Is(errTarget, db.ErrNotFound) {
+			notFound(c, "Target client not found")
+			return
+		} else if errTarget != nil {
+			internalError(c, "Failed to load client", errTarget)
+			return
+		}
+		err = db.TrashClientWithTransfer(h.Store, id, targetID, time.Now().Unix())
+	default:
+		badRequest(c, "mode must be cascade or transfer")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete client"})
+		respondError(c, err, "Failed to delete client")
 		return
 	}
+	if err := QueueClientDeleted(h.Store, client, mode, targetID, time.Now()); err != nil {
+		logger(c).Error("failed to queue webhook event", "event", db.EventClientDeleted, "err", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
@@ -1,16 +1,38 @@
 package api
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/celerix-dev/celerix-flow/internal/backup"
 	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/events"
+	"github.com/celerix-dev/celerix-flow/internal/fsck"
+	"github.com/celerix-dev/celerix-flow/internal/mail"
+	"github.com/celerix-dev/celerix-flow/internal/mail/smtptest"
+	"github.com/celerix-dev/celerix-flow/internal/metrics"
+	"github.com/celerix-dev/celerix-flow/internal/openapi"
+	"github.com/celerix-dev/celerix-flow/internal/search"
+	"github.com/celerix-dev/celerix-flow/internal/webhook"
 	_ "github.com/celerix-dev/celerix-store/pkg/engine"
 	"github.com/celerix-dev/celerix-store/pkg/sdk"
 	"github.com/gin-gonic/gin"
@@ -35,8 +57,11 @@ func setupTestHandler(t *testing.T) (*Handler, string, func()) {
 		t.Fatalf("failed to init store: %v", err)
 	}
 
+	index := search.NewIndex()
 	h := &Handler{
-		Store:            store,
+		Store:            search.Watch(store, index),
+		Index:            index,
+		Events:           events.NewBus(),
 		StorageDir:       storageDir,
 		AdminSecret:      "test-secret",
 		VersionConfig:    []byte(`{"version": "1.0.0-test"}`),
@@ -369,3 +394,2134 @@ func TestFileUploadAndList(t *testing.T) {
 		t.Errorf("expected file record NOT to be in OLD persona anymore")
 	}
 }
+
+func TestTrashAndRestore(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.POST("/upload", h.UploadFile)
+	router.GET("/files", h.ListFiles)
+	router.DELETE("/files/:id", h.DeleteFile)
+	router.GET("/trash", h.ListTrash)
+	router.POST("/trash/files/:id/restore", h.RestoreFile)
+	router.POST("/store/:key", h.SaveGeneric)
+
+	clientID := "trash-client-id"
+
+	// 1. Upload and delete a file
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "trash.txt")
+	part.Write([]byte("soon in the bin"))
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	var uploadResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &uploadResp)
+	fileID := uploadResp["id"].(string)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/files/"+fileID, nil)
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("DeleteFile failed: %v", w.Body.String())
+	}
+
+	// Blob must survive a soft delete
+	if _, err := os.Stat(filepath.Join(storageDir, fileID)); err != nil {
+		t.Errorf("expected blob to remain on disk after soft delete: %v", err)
+	}
+
+	// 2. File is gone from the listing but present in the trash
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/files", nil)
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	var listResp db.FileListResponse
+	json.Unmarshal(w.Body.Bytes(), &listResp)
+	if listResp.Total != 0 {
+		t.Errorf("expected no files after delete, got %d", listResp.Total)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/trash", nil)
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	var trashResp TrashResponse
+	json.Unmarshal(w.Body.Bytes(), &trashResp)
+	if len(trashResp.Files) != 1 || trashResp.Files[0].ID != fileID {
+		t.Fatalf("expected trashed file %s, got %v", fileID, w.Body.String())
+	}
+
+	// 3. Another client cannot restore it
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/trash/files/"+fileID+"/restore", nil)
+	req.Header.Set("X-Client-ID", "someone-else")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 restoring someone else's file, got %d", w.Code)
+	}
+
+	// 4. Owner restores it
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/trash/files/"+fileID+"/restore", nil)
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("RestoreFile failed: %v", w.Body.String())
+	}
+
+	if _, err := db.GetFileRecord(h.Store, fileID); err != nil {
+		t.Errorf("expected restored file record, got %v", err)
+	}
+
+	// 5. Delete again and purge: the blob is removed for good
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/files/"+fileID, nil)
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+
+	purged, err := h.PurgeTrash(time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 purged item, got %d (%v)", purged, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(storageDir, fileID)); !os.IsNotExist(err) {
+		t.Errorf("expected blob to be removed after purge")
+	}
+	if _, err := db.GetTrashedFileRecord(h.Store, fileID); err == nil {
+		t.Errorf("expected trashed record to be purged")
+	}
+
+	// 6. Trash records cannot be forged through the generic store, and the
+	// purger never deletes outside the storage directory
+	outside := filepath.Join(filepath.Dir(storageDir), "precious.txt")
+	os.WriteFile(outside, []byte("not yours"), 0644)
+	forged := `{"id": "forged", "stored_path": "` + outside + `", "deleted_at": 1, "owner_id": "` + clientID + `"}`
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/store/"+db.TrashFileKeyPrefix+"forged", strings.NewReader(forged))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Client-ID", clientID)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a trash key, got %d", w.Code)
+	}
+	for _, key := range []string{db.EmailPrefsKey, db.NotificationsKey, db.RemindedKey, db.FileKeyPrefix + "forged", db.TransferredKeyPrefix + "x:PROJECTS"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/store/"+key, strings.NewReader(`{"email": "someone@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", clientID)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for the server-managed key %s, got %d", key, w.Code)
+		}
+	}
+
+	h.Store.Set(clientID, db.AppID, db.TrashFileKeyPrefix+"forged", db.FileRecord{ID: "forged", StoredPath: outside, DeletedAt: 1, OwnerID: clientID})
+	if _, err := h.PurgeTrash(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected the file outside the storage directory to survive the purge: %v", err)
+	}
+}
+
+func TestDeleteClientCascadeAndTransfer(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.DELETE("/clients/:id", h.DeleteClient)
+	router.POST("/trash/clients/:id/restore", h.RestoreClient)
+
+	now := time.Now().Unix()
+	db.UpsertClient(h.Store, "admin", "Admin", "ADMIN001", now)
+	db.UpdateClientAdminStatus(h.Store, "admin", true)
+	db.UpsertClient(h.Store, "leaver", "Leaver", "LEAVER01", now)
+	db.UpsertClient(h.Store, "heir", "Heir", "HEIR0001", now)
+	db.UpsertClient(h.Store, "gone", "Gone", "GONE0001", now)
+
+	addFile := func(id, owner string) string {
+		path := filepath.Join(storageDir, id)
+		os.WriteFile(path, []byte(id), 0644)
+		db.SaveFileRecord(h.Store, db.FileRecord{ID: id, OriginalName: id + ".txt", StoredPath: path, OwnerID: owner})
+		return path
+	}
+	addFile("leaver-file", "leaver")
+	gonePath := addFile("gone-file", "gone")
+
+	h.Store.Set("leaver", "flow", "kanban", map[string]any{"columns": []any{map[string]any{"id": "l1", "cards": []any{
+		map[string]any{"id": "lc", "title": "Leaver's", "projectId": "lp", "assignee": "lee"}}}}})
+	h.Store.Set("leaver", "flow", "PROJECTS", map[string]any{"projects": []any{
+		map[string]any{"id": "lp", "name": "Leaving", "tag": "leaving", "users": []any{map[string]any{"id": "lu", "nickname": "lee"}}}}})
+	h.Store.Set("heir", "flow", "kanban", map[string]any{"columns": []any{map[string]any{"id": "h1"}}})
+	h.Store.Set("heir", "flow", "PROJECTS", map[string]any{"projects": []any{}})
+	h.Store.Set("gone", "flow", "kanban", map[string]any{"columns": []any{}})
+	db.SaveEmailPrefs(h.Store, "leaver", db.EmailPrefs{Email: "leaver@example.com", DueReminders: true})
+	db.AddNotifications(h.Store, "leaver", []db.Notification{{Type: db.NotificationOverdue, Owner: "leaver", CardID: "c1", DueDate: "2030-01-01"}}, time.Now())
+
+	// 1. Transfer requires an existing target
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/clients/leaver?mode=transfer&target=nobody", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown transfer target, got %d", w.Code)
+	}
+
+	// 2. Transfer ownership to heir
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/clients/leaver?mode=transfer&target=heir", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("transfer delete failed: %v", w.Body.String())
+	}
+
+	record, err := db.GetFileRecord(h.Store, "leaver-file")
+	if err != nil || record.OwnerID != "heir" || record.OwnerName != "Heir" {
+		t.Errorf("expected file to be owned by heir, got %+v (%v)", record, err)
+	}
+
+	board, _ := sdk.Get[map[string]any](h.Store, "heir", "flow", "kanban")
+	if cols, _ := board["columns"].([]any); len(cols) != 2 {
+		t.Errorf("expected merged kanban with 2 columns, got %v", board["columns"])
+	}
+	if _, err := h.Store.Get("heir", "flow", db.TransferredKeyPrefix+"leaver:PROJECTS"); err != nil {
+		t.Errorf("expected conflicting key to be kept under the transferred prefix")
+	}
+	// The heir keeps its own projects, so the leaver's cards lose theirs
+	merged, _, _ := db.GetKanban(h.Store, "heir")
+	if card := merged.Columns[1].Cards[0]; card.ID != "lc" || card.ProjectID != "" || card.Assignee != "" {
+		t.Errorf("expected the transferred card to drop references to the leaver's projects, got %+v", card)
+	}
+	// The leaver's mail settings and notifications are not the heir's
+	if prefs, _ := db.GetEmailPrefs(h.Store, "heir"); prefs.Email != "" || prefs.DueReminders {
+		t.Errorf("expected the heir's email preferences to be untouched, got %+v", prefs)
+	}
+	for _, key := range []string{db.EmailPrefsKey, db.NotificationsKey, db.RemindedKey} {
+		for _, persona := range []string{"heir", "leaver"} {
+			if _, err := h.Store.Get(persona, "flow", key); err == nil {
+				t.Errorf("expected %s of the leaver to be dropped, found it on %s", key, persona)
+			}
+		}
+		if _, err := h.Store.Get("heir", "flow", db.TransferredKeyPrefix+"leaver:"+key); err == nil {
+			t.Errorf("expected %s not to be transferred", key)
+		}
+	}
+
+	// 3. Cascade delete trashes the client together with their files
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/clients/gone", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("cascade delete failed: %v", w.Body.String())
+	}
+	if _, err := db.GetFileRecord(h.Store, "gone-file"); err == nil {
+		t.Errorf("expected file of deleted client to be trashed")
+	}
+
+	// 4. Restoring brings the files back
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/trash/clients/gone/restore", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("RestoreClient failed: %v", w.Body.String())
+	}
+	if _, err := db.GetFileRecord(h.Store, "gone-file"); err != nil {
+		t.Errorf("expected file to be restored with its client: %v", err)
+	}
+
+	// 5. Delete again and purge: blobs and store keys are removed
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/clients/gone", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if _, err := h.PurgeTrash(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash failed: %v", err)
+	}
+	if _, err := os.Stat(gonePath); !os.IsNotExist(err) {
+		t.Errorf("expected blob of purged client to be removed")
+	}
+	if _, err := h.Store.Get("gone", "flow", "kanban"); err == nil {
+		t.Errorf("expected kanban of purged client to be removed")
+	}
+	if _, err := db.GetTrashedClient(h.Store, "gone"); err == nil {
+		t.Errorf("expected trashed client record to be purged")
+	}
+}
+
+func TestIntegrityCheck(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/admin/fsck", h.CheckIntegrity)
+	router.POST("/admin/fsck", h.CheckIntegrity)
+
+	now := time.Now().Unix()
+	db.UpsertClient(h.Store, "admin", "Admin", "ADMIN001", now)
+	db.UpdateClientAdminStatus(h.Store, "admin", true)
+	db.UpsertClient(h.Store, "owner", "Owner", "OWNER001", now)
+
+	// A healthy file, a record without blob, a blob without record,
+	// a wrong size and a file of a client that no longer exists
+	write := func(name, content string) string {
+		path := filepath.Join(storageDir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "ok", StoredPath: write("ok", "12345"), Size: 5, OwnerID: "owner"})
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "dangling", StoredPath: filepath.Join(storageDir, "dangling"), OwnerID: "owner"})
+	write("orphan", "nobody loves me")
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(storageDir, "orphan"), old, old)
+	// An upload in flight has stored its blob but not yet its record
+	write("uploading", "almost there")
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "resized", StoredPath: write("resized", "123"), Size: 99, OwnerID: "owner"})
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "ownerless", StoredPath: write("ownerless", "x"), Size: 1, OwnerID: "ghost"})
+
+	run := func(method string) fsck.Report {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/admin/fsck", nil)
+		req.Header.Set("X-Client-ID", "admin")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("fsck %s failed: %v", method, w.Body.String())
+		}
+		var report fsck.Report
+		json.Unmarshal(w.Body.Bytes(), &report)
+		return report
+	}
+
+	// 1. Report only
+	report := run("GET")
+	kinds := map[fsck.IssueKind]int{}
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+		if issue.Repaired {
+			t.Errorf("expected no repairs in report mode, got %+v", issue)
+		}
+	}
+	for _, kind := range []fsck.IssueKind{fsck.OrphanBlob, fsck.DanglingRecord, fsck.MissingOwner, fsck.SizeMismatch} {
+		if kinds[kind] != 1 {
+			t.Errorf("expected exactly one %s issue, got %d", kind, kinds[kind])
+		}
+	}
+
+	// 2. Repair, then a second check is clean
+	report = run("POST")
+	if report.Unresolved() != 0 {
+		t.Errorf("expected every issue to be repaired, got %+v", report.Issues)
+	}
+
+	report = run("GET")
+	if len(report.Issues) != 0 {
+		t.Errorf("expected clean report after repair, got %+v", report.Issues)
+	}
+
+	record, err := db.GetFileRecord(h.Store, "ownerless")
+	if err != nil || record.OwnerID != "" {
+		t.Errorf("expected ownerless file to be handed to the admin, got %+v (%v)", record, err)
+	}
+	if _, err := os.Stat(filepath.Join(storageDir, "orphan")); !os.IsNotExist(err) {
+		t.Errorf("expected orphan blob to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(storageDir, "uploading")); err != nil {
+		t.Errorf("expected a fresh blob to be left alone: %v", err)
+	}
+
+	// 3. A blob that cannot be checked is reported, not repaired as dangling
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "unreadable", StoredPath: filepath.Join(storageDir, "ok", "child"), OwnerID: "owner"})
+	report = run("POST")
+	if len(report.Issues) != 1 || report.Issues[0].Kind != fsck.UnreadableBlob || report.Issues[0].Repaired {
+		t.Errorf("expected one unrepaired unreadable_blob issue, got %+v", report.Issues)
+	}
+	if _, err := db.GetFileRecord(h.Store, "unreadable"); err != nil {
+		t.Errorf("expected the record of an unreadable blob to be kept: %v", err)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.POST("/upload", h.UploadFile)
+	router.GET("/admin/backup", h.ExportBackup)
+	router.POST("/admin/restore", h.RestoreBackup)
+
+	now := time.Now().Unix()
+	db.UpsertClient(h.Store, "admin", "Admin", "ADMIN001", now)
+	db.UpdateClientAdminStatus(h.Store, "admin", true)
+	h.Store.Set("admin", "flow", "kanban", map[string]any{"columns": []any{map[string]any{"id": "c1"}}})
+
+	// 1. Upload a file and take a backup
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "keep.txt")
+	part.Write([]byte("precious"))
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	var uploadResp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &uploadResp)
+	fileID := uploadResp["id"].(string)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/backup", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExportBackup failed: %v", w.Body.String())
+	}
+	archive := w.Body.Bytes()
+
+	// 2. Wreck the instance
+	h.Store.Delete("admin", "flow", "kanban")
+	os.Remove(filepath.Join(storageDir, fileID))
+	h.Store.Set("admin", "flow", "scratch", "not in the backup")
+
+	restore := func(data []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "backup.tar.gz")
+		part.Write(data)
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/restore", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-Client-ID", "admin")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 3. Garbage is rejected without touching the data
+	if w := restore([]byte("not a backup")); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid archive, got %d", w.Code)
+	}
+	manifest := `{"schema_version":1,"kind":"instance","personas":["admin"],"blobs":[{"name":"b1","size":4}]}`
+	for name, data := range map[string][]byte{
+		"persona name": buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"store/../admin.json", "{}"}),
+		"oversized":    buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"blobs/b1", "too large"}),
+		"unlisted":     buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"blobs/b2", "blob"}),
+	} {
+		if w := restore(data); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	if _, err := h.Store.Get("admin", "flow", "scratch"); err != nil {
+		t.Errorf("expected data to be untouched after a rejected restore")
+	}
+
+	// 4. Restore the backup
+	if w := restore(archive); w.Code != http.StatusOK {
+		t.Fatalf("RestoreBackup failed: %v", w.Body.String())
+	}
+
+	if _, err := h.Store.Get("admin", "flow", "kanban"); err != nil {
+		t.Errorf("expected kanban to be restored")
+	}
+	if _, err := h.Store.Get("admin", "flow", "scratch"); err == nil {
+		t.Errorf("expected keys missing from the backup to be removed")
+	}
+
+	record, err := db.GetFileRecord(h.Store, fileID)
+	if err != nil {
+		t.Fatalf("expected file record to be restored: %v", err)
+	}
+	content, err := os.ReadFile(record.StoredPath)
+	if err != nil || string(content) != "precious" {
+		t.Errorf("expected restored blob content, got %q (%v)", content, err)
+	}
+
+	// 5. A blob that cannot be moved into place rolls the store back
+	h.Store.Set("admin", "flow", "scratch", "kept")
+	os.MkdirAll(filepath.Join(record.StoredPath+".deleting", "in-the-way"), 0755)
+	if w := restore(archive); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the restore to fail, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := h.Store.Get("admin", "flow", "scratch"); err != nil {
+		t.Errorf("expected the store to be rolled back after a failed blob move")
+	}
+	if content, _ := os.ReadFile(record.StoredPath); string(content) != "precious" {
+		t.Errorf("expected the existing blob to be kept, got %q", content)
+	}
+}
+
+func TestPersonaExportAndImport(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.POST("/upload", h.UploadFile)
+	router.GET("/persona/export", h.ExportPersona)
+	router.POST("/persona/import", h.ImportPersona)
+
+	h.Store.Set("alice", "flow", "kanban", map[string]any{"columns": []any{map[string]any{"id": "a1"}}})
+	h.Store.Set("alice", "flow", "TEMPLATES", []any{"weekly"})
+	h.Store.Set("bob", "flow", "WIDGETS", []any{"clock"})
+
+	upload := func(clientID, name, content string) string {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", name)
+		part.Write([]byte(content))
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-Client-ID", clientID)
+		router.ServeHTTP(w, req)
+
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp["id"].(string)
+	}
+	aliceFile := upload("alice", "notes.txt", "alice's notes")
+	bobFile := upload("bob", "old.txt", "bob's old file")
+
+	importAs := func(clientID, mode string, data []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "export.tar.gz")
+		part.Write(data)
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/persona/import?mode="+mode, body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-Client-ID", clientID)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 1. Alice exports her data
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/persona/export", nil)
+	req.Header.Set("X-Client-ID", "alice")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExportPersona failed: %v", w.Body.String())
+	}
+	archive := w.Body.Bytes()
+
+	// 2. Bob merges it: his own data stays, alice's data is added
+	if w := importAs("bob", "merge", archive); w.Code != http.StatusOK {
+		t.Fatalf("merge import failed: %v", w.Body.String())
+	}
+	if _, err := h.Store.Get("bob", "flow", "kanban"); err != nil {
+		t.Errorf("expected kanban to be imported")
+	}
+	if _, err := h.Store.Get("bob", "flow", "WIDGETS"); err != nil {
+		t.Errorf("expected existing keys to survive a merge")
+	}
+
+	files, _ := db.GetFileRecordsByOwner(h.Store, "bob")
+	var imported *db.FileRecord
+	for i, f := range files {
+		if f.OwnerID == "bob" && f.OriginalName == "notes.txt" {
+			imported = &files[i]
+		}
+	}
+	if imported == nil || imported.ID == aliceFile {
+		t.Fatalf("expected notes.txt to be imported under a new ID, got %+v", files)
+	}
+	if content, _ := os.ReadFile(imported.StoredPath); string(content) != "alice's notes" {
+		t.Errorf("expected imported blob content, got %q", content)
+	}
+
+	// 3. Replace drops bob's keys and trashes his files
+	if w := importAs("bob", "replace", archive); w.Code != http.StatusOK {
+		t.Fatalf("replace import failed: %v", w.Body.String())
+	}
+	if _, err := h.Store.Get("bob", "flow", "WIDGETS"); err == nil {
+		t.Errorf("expected existing keys to be dropped on replace")
+	}
+	if _, err := db.GetTrashedFileRecord(h.Store, bobFile); err != nil {
+		t.Errorf("expected replaced file to be moved to the trash")
+	}
+
+	// 4. Alice's own data is untouched
+	if _, err := db.GetFileRecord(h.Store, aliceFile); err != nil {
+		t.Errorf("expected the exporting client's file to be untouched")
+	}
+
+	// 5. File records pointing outside the extracted blobs are refused
+	manifest := `{"schema_version":1,"kind":"persona","personas":["mallory"],"blobs":[]}`
+	for _, stored := range []string{"", "/", ".."} {
+		record := `{"file:evil":{"id":"evil","original_name":"evil","stored_path":"` + stored + `"}}`
+		data := buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"store/mallory.json", record})
+		if w := importAs("carol", "merge", data); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for stored path %q, got %d: %s", stored, w.Code, w.Body.String())
+		}
+	}
+	if files, _ := db.GetFileRecordsByOwner(h.Store, "carol"); len(files) != 0 {
+		t.Errorf("expected no files to be imported, got %+v", files)
+	}
+
+	// 6. Boards and projects follow the API's rules; mail settings and
+	// notifications stay with the importing client
+	for name, store := range map[string]string{
+		"dangling project": `{"kanban": {"columns": [{"id": "c", "cards": [{"id": "k1", "title": "x", "projectId": "nope"}]}]}}`,
+		"duplicate tags":   `{"PROJECTS": {"projects": [{"id": "p1", "name": "A", "tag": "t"}, {"id": "p2", "name": "B", "tag": "T"}]}}`,
+		"unknown client":   `{"PROJECTS": {"projects": [{"id": "p1", "name": "A", "tag": "t", "users": [{"nickname": "x", "clientId": "ghost"}]}]}}`,
+	} {
+		data := buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"store/mallory.json", store})
+		if w := importAs("carol", "merge", data); w.Code < 400 || w.Code >= 500 {
+			t.Errorf("expected the %s import to be refused, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	if _, err := h.Store.Get("carol", "flow", "kanban"); err == nil {
+		t.Errorf("expected no board to be imported")
+	}
+	valid := `{"PROJECTS": {"projects": [{"id": "p1", "name": "A", "tag": "t"}]},
+		"kanban": {"columns": [{"id": "c", "cards": [{"id": "k1", "title": "x", "projectId": "p1"}]}]},
+		"email_prefs": {"email": "mallory@example.com", "due_reminders": true}}`
+	data := buildArchive(t, archiveEntry{"manifest.json", manifest}, archiveEntry{"store/mallory.json", valid})
+	if w := importAs("carol", "merge", data); w.Code != http.StatusOK {
+		t.Fatalf("expected a valid board to be imported, got %d: %s", w.Code, w.Body.String())
+	}
+	if board, _, err := db.GetKanban(h.Store, "carol"); err != nil || board.Columns[0].Cards[0].Version != db.KanbanCardVersion {
+		t.Errorf("expected the imported board to be normalized, got %+v (%v)", board, err)
+	}
+	if prefs, _ := db.GetEmailPrefs(h.Store, "carol"); prefs.Email != "" {
+		t.Errorf("expected email preferences not to be imported, got %+v", prefs)
+	}
+}
+
+type archiveEntry struct {
+	name, content string
+}
+
+// buildArchive writes a backup archive with the given entries, in order.
+func buildArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestHealthAndMetrics(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	h.Store = metrics.InstrumentStore(h.Store)
+	reg := metrics.NewRegistry()
+	h.RegisterMetrics(reg)
+
+	router := gin.Default()
+	router.Use(metrics.Middleware())
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	router.GET("/metrics", metrics.Handler)
+	router.POST("/upload", h.UploadFile)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("expected healthz 200, got %d", w.Code)
+	}
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("expected readyz 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Upload a file so the upload counter moves
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, _ := mw.CreateFormFile("file", "hello.txt")
+	part.Write([]byte("hello metrics"))
+	mw.Close()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-Client-ID", "client-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	db.UpsertClient(h.Store, "client-1", "Client", "CLIENT01", time.Now().Unix())
+
+	w := get("/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected metrics 200, got %d", w.Code)
+	}
+	out := w.Body.String()
+	var own bytes.Buffer
+	reg.Write(&own)
+	out += own.String()
+
+	for _, want := range []string{
+		`flow_http_requests_total{method="GET",route="/healthz",status="200"}`,
+		`flow_http_request_duration_seconds_count{method="GET",route="/readyz"}`,
+		`flow_store_operation_duration_seconds_count{op="get_personas"}`,
+		"flow_active_clients 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+	if !strings.Contains(out, "flow_upload_bytes_total ") || strings.Contains(out, "flow_upload_bytes_total 0\n") {
+		t.Errorf("expected upload bytes to be counted")
+	}
+
+	// An unwritable storage directory makes the instance unready
+	os.RemoveAll(storageDir)
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected readyz 503 without storage, got %d", w.Code)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/kanban", h.GetKanban)
+	router.GET("/files/:id", h.GetFileMetadata)
+	router.DELETE("/clients/:id", h.DeleteClient)
+	router.POST("/trash/clients/:id/restore", h.RestoreClient)
+	router.NoRoute(RouteNotFound)
+
+	now := time.Now().Unix()
+	db.UpsertClient(h.Store, "admin", "Admin", "ADMIN001", now)
+	db.UpdateClientAdminStatus(h.Store, "admin", true)
+	db.UpsertClient(h.Store, "twin", "Twin", "TWIN0001", now)
+
+	do := func(method, path, clientID string) (int, ErrorResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if clientID != "" {
+			req.Header.Set("X-Client-ID", clientID)
+		}
+		router.ServeHTTP(w, req)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	tests := []struct {
+		name, method, path, clientID string
+		status                       int
+		code                         string
+	}{
+		{"missing header", "GET", "/kanban", "", http.StatusBadRequest, CodeBadRequest},
+		{"missing file", "GET", "/files/nope", "admin", http.StatusNotFound, CodeNotFound},
+		{"not admin", "DELETE", "/clients/twin", "twin", http.StatusForbidden, CodeForbidden},
+		{"bad transfer target", "DELETE", "/clients/twin?mode=transfer&target=ghost", "admin", http.StatusNotFound, CodeNotFound},
+		{"unknown route", "GET", "/nowhere", "", http.StatusNotFound, CodeNotFound},
+	}
+	for _, tt := range tests {
+		status, resp := do(tt.method, tt.path, tt.clientID)
+		if status != tt.status || resp.Error.Code != tt.code || resp.Error.Message == "" {
+			t.Errorf("%s: expected %d %s, got %d %+v", tt.name, tt.status, tt.code, status, resp.Error)
+		}
+	}
+
+	// A client that exists again cannot be restored over: conflict
+	db.TrashClientCascade(h.Store, "twin", now)
+	db.UpsertClient(h.Store, "twin", "Twin Again", "TWIN0002", now)
+	status, resp := do("POST", "/trash/clients/twin/restore", "admin")
+	if status != http.StatusConflict || resp.Error.Code != CodeConflict {
+		t.Errorf("expected 409 conflict on restore, got %d %+v", status, resp.Error)
+	}
+
+	// Missing kanban data is not an error
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/kanban", nil)
+	req.Header.Set("X-Client-ID", "admin")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected empty kanban for a new client, got %d", w.Code)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	doc, err := openapi.Parse(OpenAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	// Every registered route is documented and every documented route exists
+	registered := map[string]bool{}
+	for _, r := range router.Routes() {
+		path := strings.TrimPrefix(r.Path, "/api")
+		segments := strings.Split(path, "/")
+		for i, s := range segments {
+			if strings.HasPrefix(s, ":") {
+				segments[i] = "{" + s[1:] + "}"
+			}
+		}
+		registered[r.Method+" "+strings.Join(segments, "/")] = true
+	}
+	documented := map[string]bool{}
+	for _, r := range doc.Routes() {
+		documented[r.Method+" "+r.Path] = true
+		if !registered[r.Method+" "+r.Path] {
+			t.Errorf("%s %s is documented but not registered", r.Method, r.Path)
+		}
+	}
+	for route := range registered {
+		if !documented[route] {
+			t.Errorf("%s is registered but not documented", route)
+		}
+	}
+
+	for _, ref := range doc.Refs() {
+		if !doc.Resolve(ref) {
+			t.Errorf("unresolved reference %s", ref)
+		}
+	}
+
+	// Schemas of response bodies list exactly the JSON fields of their types
+	types := map[string]reflect.Type{
+		"FileRecord":         reflect.TypeOf(db.FileRecord{}),
+		"FileList":           reflect.TypeOf(db.FileListResponse{}),
+		"ClientRecord":       reflect.TypeOf(db.ClientRecord{}),
+		"Trash":              reflect.TypeOf(TrashResponse{}),
+		"IntegrityReport":    reflect.TypeOf(fsck.Report{}),
+		"IntegrityIssue":     reflect.TypeOf(fsck.Issue{}),
+		"Manifest":           reflect.TypeOf(backup.Manifest{}),
+		"BlobEntry":          reflect.TypeOf(backup.BlobEntry{}),
+		"Projects":           reflect.TypeOf(db.ProjectsData{}),
+		"Project":            reflect.TypeOf(db.Project{}),
+		"ProjectUser":        reflect.TypeOf(db.ProjectUser{}),
+		"Membership":         reflect.TypeOf(db.Membership{}),
+		"BoardCard":          reflect.TypeOf(db.BoardCard{}),
+		"KanbanCard":         reflect.TypeOf(db.KanbanCard{}),
+		"ChecklistItem":      reflect.TypeOf(db.ChecklistItem{}),
+		"SearchResponse":     reflect.TypeOf(SearchResponse{}),
+		"SearchResult":       reflect.TypeOf(search.Result{}),
+		"Notification":       reflect.TypeOf(db.Notification{}),
+		"NotificationList":   reflect.TypeOf(NotificationList{}),
+		"MarkReadRequest":    reflect.TypeOf(MarkReadRequest{}),
+		"MarkReadResponse":   reflect.TypeOf(MarkReadResponse{}),
+		"EmailPrefs":         reflect.TypeOf(db.EmailPrefs{}),
+		"OutboxEntry":        reflect.TypeOf(db.OutboxEntry{}),
+		"OutboxList":         reflect.TypeOf(OutboxList{}),
+		"WebhookInput":       reflect.TypeOf(WebhookInput{}),
+		"Webhook":            reflect.TypeOf(db.Webhook{}),
+		"WebhookList":        reflect.TypeOf(WebhookList{}),
+		"WebhookEvent":       reflect.TypeOf(db.WebhookEvent{}),
+		"CardChange":         reflect.TypeOf(db.CardChange{}),
+		"ClientDeletedEvent": reflect.TypeOf(ClientDeletedEvent{}),
+		"WebhookDelivery":    reflect.TypeOf(db.WebhookDelivery{}),
+		"DeliveryList":       reflect.TypeOf(DeliveryList{}),
+		"InboundHookInput":   reflect.TypeOf(InboundHookInput{}),
+		"InboundHook":        reflect.TypeOf(InboundHook{}),
+		"InboundHookList":    reflect.TypeOf(InboundHookList{}),
+		"NewCard":            reflect.TypeOf(db.NewCard{}),
+		"APITokenInput":      reflect.TypeOf(APITokenInput{}),
+		"APIToken":           reflect.TypeOf(APIToken{}),
+		"APITokenList":       reflect.TypeOf(APITokenList{}),
+		"APIError":           reflect.TypeOf(APIError{}),
+		"ErrorResponse":      reflect.TypeOf(ErrorResponse{}),
+	}
+	for name, typ := range types {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		var want, got []string
+		for i := 0; i < typ.NumField(); i++ {
+			tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if tag != "-" {
+				want = append(want, tag)
+			}
+		}
+		for _, p := range schema.Properties {
+			got = append(got, p.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("schema %s has properties %v, %s has %v", name, got, typ, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), OpenAPISpec()) {
+		t.Errorf("expected the document at /api/openapi.json, got %d", w.Code)
+	}
+}
+
+func TestAPIVersionAlias(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	get := func(router *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	router := gin.New()
+	h.Mount(router)
+
+	w := get(router, "/api/v1/version")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Errorf("expected canonical route without deprecation, got %d %q", w.Code, w.Header().Get("Deprecation"))
+	}
+
+	w = get(router, "/api/version")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected alias to keep working, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Deprecation"), "@") {
+		t.Errorf("expected a Deprecation date, got %q", w.Header().Get("Deprecation"))
+	}
+	if link := w.Header().Get("Link"); link != `</api/v1/version>; rel="successor-version"` {
+		t.Errorf("expected successor link, got %q", link)
+	}
+	if w.Header().Get("Sunset") != "" {
+		t.Errorf("expected no Sunset without a configured date, got %q", w.Header().Get("Sunset"))
+	}
+
+	// A future sunset is announced, a past one retires the alias
+	h.AliasSunset = time.Now().Add(24 * time.Hour)
+	router = gin.New()
+	h.Mount(router)
+	w = get(router, "/api/version")
+	if w.Code != http.StatusOK || w.Header().Get("Sunset") == "" {
+		t.Errorf("expected alias with Sunset header, got %d %q", w.Code, w.Header().Get("Sunset"))
+	}
+
+	h.AliasSunset = time.Now().Add(-24 * time.Hour)
+	router = gin.New()
+	h.Mount(router)
+	w = get(router, "/api/version")
+	var resp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusGone || resp.Error.Code != CodeGone {
+		t.Errorf("expected 410 gone after sunset, got %d %+v", w.Code, resp.Error)
+	}
+	if w = get(router, "/api/v1/version"); w.Code != http.StatusOK {
+		t.Errorf("expected canonical route to outlive the alias, got %d", w.Code)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", "owner")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/projects", `{"name": " Website ", "tag": "web", "users": [{"firstName": "Ada", "nickname": "ada"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created db.Project
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.ID == "" || created.Name != "Website" || created.CreatedAt == 0 || created.Icon != db.DefaultProjectIcon {
+		t.Errorf("expected generated ID, trimmed name and defaults, got %+v", created)
+	}
+	if len(created.Users) != 1 || created.Users[0].ID == "" || created.Users[0].Version != db.ProjectUserVersion {
+		t.Errorf("expected user with generated ID and version, got %+v", created.Users)
+	}
+
+	// Tags are unique regardless of case
+	w = do("POST", "/api/projects", `{"name": "Web 2", "tag": "WEB"}`)
+	var errResp ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusConflict || errResp.Error.Code != CodeConflict {
+		t.Errorf("expected 409 for a duplicate tag, got %d %+v", w.Code, errResp.Error)
+	}
+
+	w = do("POST", "/api/projects", `{"name": "", "tag": "has space", "color": "blue"}`)
+	errResp = ErrorResponse{}
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	details, _ := errResp.Error.Details.(map[string]any)
+	if w.Code != http.StatusBadRequest || errResp.Error.Code != CodeValidation || len(details) != 3 {
+		t.Errorf("expected validation errors for name, tag and color, got %d %+v", w.Code, errResp.Error)
+	}
+
+	w = do("PUT", "/api/projects/"+created.ID, `{"name": "Website", "tag": "site", "createdAt": 1}`)
+	var updated db.Project
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Tag != "site" || updated.CreatedAt != created.CreatedAt {
+		t.Errorf("expected updated tag with kept creation time, got %d %+v", w.Code, updated)
+	}
+
+	// The generic store endpoint reads and writes the same data, validated
+	w = do("GET", "/api/store/PROJECTS", "")
+	var stored db.ProjectsData
+	json.Unmarshal(w.Body.Bytes(), &stored)
+	if len(stored.Projects) != 1 || stored.Projects[0].Tag != "site" {
+		t.Errorf("expected the project in the PROJECTS key, got %s", w.Body.String())
+	}
+	w = do("POST", "/api/store/PROJECTS", `{"version": "1.0.0", "projects": [{"id": "a", "name": "A", "tag": "x"}, {"id": "b", "name": "B", "tag": "X"}]}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected duplicate tags to be rejected through the store, got %d", w.Code)
+	}
+	w = do("POST", "/api/store/PROJECTS", `[{"id": "a", "name": "A", "tag": "x"}]`)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the legacy array format to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do("GET", "/api/projects", "")
+	var list db.ProjectsData
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Version != db.ProjectsVersion || len(list.Projects) != 1 || list.Projects[0].ID != "a" {
+		t.Errorf("expected the project saved through the store, got %d %+v", w.Code, list)
+	}
+
+	if w = do("DELETE", "/api/projects/a", ""); w.Code != http.StatusOK {
+		t.Errorf("expected delete to succeed, got %d", w.Code)
+	}
+	if w = do("GET", "/api/projects/a", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestKanbanReferences(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	do := func(method, path, body string) (int, ErrorResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", "owner")
+		router.ServeHTTP(w, req)
+		var resp ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	cards := func() map[string]db.KanbanCard {
+		board, _, err := db.GetKanban(h.Store, "owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := map[string]db.KanbanCard{}
+		board.Cards(func(_ *db.KanbanColumn, card *db.KanbanCard) { byID[card.ID] = *card })
+		return byID
+	}
+	board := func(cards string) string {
+		return `{"version": "1.0.0", "columns": [{"version": "1.0.0", "id": "todo", "title": "To do", "cards": [` + cards + `]}]}`
+	}
+
+	do("POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ada"}, {"id": "u2", "nickname": "bob"}]}`)
+	do("POST", "/api/projects", `{"id": "p2", "name": "Two", "tag": "two", "users": [{"id": "u3", "nickname": "ada"}]}`)
+
+	valid := `{"id": "c1", "title": "A", "createdAt": 1, "projectId": "p1", "assignee": "ada"},
+		{"id": "c2", "title": "B", "createdAt": 1, "projectId": "p1", "assignee": "bob"},
+		{"id": "c3", "title": "C", "createdAt": 1}`
+	if status, resp := do("POST", "/api/kanban", board(valid)); status != http.StatusOK {
+		t.Fatalf("expected valid board to be saved, got %d %+v", status, resp.Error)
+	}
+
+	// New dangling references are rejected with the offending cards listed
+	status, resp := do("POST", "/api/kanban", board(valid+`, {"id": "c4", "title": "D", "createdAt": 1, "projectId": "ghost"},
+		{"id": "c5", "title": "E", "createdAt": 1, "projectId": "p2", "assignee": "bob"}`))
+	details, _ := resp.Error.Details.([]any)
+	if status != http.StatusBadRequest || resp.Error.Code != CodeValidation || len(details) != 2 {
+		t.Errorf("expected two dangling references, got %d %+v", status, resp.Error)
+	}
+	if status, _ := do("POST", "/api/store/kanban", board(`{"id": "c4", "title": "D", "createdAt": 1, "projectId": "ghost"}`)); status != http.StatusBadRequest {
+		t.Errorf("expected the store endpoint to check kanban references too, got %d", status)
+	}
+
+	// References that were already broken do not block saving the board
+	h.Store.Set("owner", db.AppID, db.KanbanKey, map[string]any{"columns": []any{map[string]any{
+		"id": "todo", "title": "To do", "cards": []any{map[string]any{"id": "old", "title": "Old", "projectId": "gone"}},
+	}}})
+	if status, resp := do("POST", "/api/kanban", board(valid+`, {"id": "old", "title": "Old", "createdAt": 1, "projectId": "gone"}`)); status != http.StatusOK {
+		t.Fatalf("expected legacy references to be kept, got %d %+v", status, resp.Error)
+	}
+
+	// Removing a referenced project is blocked by default
+	status, resp = do("DELETE", "/api/projects/p1", "")
+	blocked, _ := resp.Error.Details.(map[string]any)
+	if status != http.StatusConflict || len(blocked["cards"].([]any)) != 2 {
+		t.Errorf("expected 409 listing the two cards, got %d %+v", status, resp.Error)
+	}
+
+	// Renamed nicknames are followed, removed users cleared on request
+	status, resp = do("PUT", "/api/projects/p1?cascade=clear", `{"name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ada2"}]}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected update with cascade=clear, got %d %+v", status, resp.Error)
+	}
+	if c := cards(); c["c1"].Assignee != "ada2" || c["c2"].Assignee != "" || c["c2"].ProjectID != "p1" {
+		t.Errorf("expected followed nickname and cleared assignee, got %+v %+v", c["c1"], c["c2"])
+	}
+
+	if status, _ := do("DELETE", "/api/projects/p1/users/u1?cascade=reassign&target=u3", ""); status != http.StatusBadRequest {
+		t.Errorf("expected a target outside the project to be rejected, got %d", status)
+	}
+	if status, _ := do("DELETE", "/api/projects/p1?cascade=reassign", ""); status != http.StatusBadRequest {
+		t.Errorf("expected reassign without target to be rejected, got %d", status)
+	}
+
+	// Reassigned cards keep their assignee only if the target project has them
+	if status, resp := do("DELETE", "/api/projects/p1?cascade=reassign&target=p2", ""); status != http.StatusOK {
+		t.Fatalf("expected reassign to succeed, got %d %+v", status, resp.Error)
+	}
+	if c := cards(); c["c1"].ProjectID != "p2" || c["c1"].Assignee != "" || c["old"].ProjectID != "gone" {
+		t.Errorf("expected cards moved to p2 and unrelated cards untouched, got %+v %+v", c["c1"], c["old"])
+	}
+
+	if status, _ := do("DELETE", "/api/projects/p2?cascade=clear", ""); status != http.StatusOK {
+		t.Errorf("expected delete with cascade=clear, got %d", status)
+	}
+	if c := cards(); c["c1"].ProjectID != "" || c["c2"].ProjectID != "" {
+		t.Errorf("expected project references cleared, got %+v %+v", c["c1"], c["c2"])
+	}
+}
+
+func TestProjectMembership(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", client)
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"owner", "alice", "bob", "mallory"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var resp ErrorResponse
+	if status := do("owner", "POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "ghost"}]}`, &resp); status != http.StatusBadRequest {
+		t.Errorf("expected unknown clients to be rejected, got %d %+v", status, resp.Error)
+	}
+	if status := do("owner", "POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}, {"id": "u2", "nickname": "al", "clientId": "alice"}]}`, nil); status != http.StatusBadRequest {
+		t.Errorf("expected a client linked twice in one project to be rejected, got %d", status)
+	}
+	if status := do("owner", "POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}, {"id": "u2", "nickname": "bob", "clientId": "bob"}]}`, &resp); status != http.StatusCreated {
+		t.Fatalf("expected project with a linked user, got %d %+v", status, resp.Error)
+	}
+	do("owner", "POST", "/api/projects", `{"id": "p2", "name": "Two", "tag": "two"}`, nil)
+	board := `{"columns": [{"id": "todo", "title": "To do", "purpose": "todo", "cards": [
+		{"id": "c1", "title": "Later", "createdAt": 1, "projectId": "p1", "assignee": "ali", "dueDate": "2030-01-02"},
+		{"id": "c2", "title": "Soon", "createdAt": 2, "projectId": "p1", "assignee": "ALI", "dueDate": "2030-01-01"},
+		{"id": "c3", "title": "Bob's", "createdAt": 3, "projectId": "p1", "assignee": "bob"},
+		{"id": "c4", "title": "Private", "createdAt": 4, "projectId": "p2"}]}]}`
+	if status := do("owner", "POST", "/api/kanban", board, &resp); status != http.StatusOK {
+		t.Fatalf("expected board to be saved, got %d %+v", status, resp.Error)
+	}
+
+	// Members learn the owner's handle and name, never client IDs but
+	// their own
+	var memberships []db.Membership
+	if status := do("alice", "GET", "/api/me/projects", "", &memberships); status != http.StatusOK ||
+		len(memberships) != 1 || memberships[0].User.ID != "u1" {
+		t.Fatalf("expected alice to be a member of p1, got %d %+v", status, memberships)
+	}
+	handle := memberships[0].Owner
+	if handle != db.OwnerHandle("owner") || memberships[0].OwnerName != "owner" {
+		t.Errorf("expected the owner's handle and name, got %+v", memberships[0])
+	}
+	if users := memberships[0].Project.Users; users[0].ClientID != "alice" || users[1].ClientID != "" {
+		t.Errorf("expected other members' client IDs to be hidden, got %+v", users)
+	}
+
+	var work []db.BoardCard
+	if status := do("alice", "GET", "/api/me/work", "", &work); status != http.StatusOK || len(work) != 2 ||
+		work[0].Card.ID != "c2" || work[1].Card.ID != "c1" || work[0].ColumnPurpose != "todo" || work[0].ProjectName != "One" ||
+		work[0].Owner != handle || work[0].OwnerName != "owner" {
+		t.Errorf("expected alice's two cards, soonest first, got %d %+v", status, work)
+	}
+	if status := do("mallory", "GET", "/api/me/work", "", &work); status != http.StatusOK || len(work) != 0 {
+		t.Errorf("expected no work for an unlinked client, got %d %+v", status, work)
+	}
+
+	// Members see the project's cards on the owner's board and nothing else
+	var shared db.KanbanBoard
+	for _, path := range []string{"/api/projects/p1/board?owner=" + handle, "/api/projects/p1/board"} {
+		if status := do("alice", "GET", path, "", &shared); status != http.StatusOK ||
+			len(shared.Columns) != 1 || len(shared.Columns[0].Cards) != 3 {
+			t.Errorf("expected the three cards of p1 at %s, got %d %+v", path, status, shared)
+		}
+	}
+	if status := do("alice", "GET", "/api/projects/p1/board?owner=owner", "", nil); status != http.StatusNotFound {
+		t.Errorf("expected raw client IDs not to work as a handle, got %d", status)
+	}
+	if status := do("alice", "GET", "/api/projects/p2/board?owner="+handle, "", nil); status != http.StatusNotFound {
+		t.Errorf("expected other projects to stay hidden from members, got %d", status)
+	}
+	if status := do("mallory", "GET", "/api/projects/p1/board?owner="+handle, "", nil); status != http.StatusNotFound {
+		t.Errorf("expected outsiders to be refused, got %d", status)
+	}
+	if status := do("owner", "GET", "/api/projects/p2/board", "", &shared); status != http.StatusOK || len(shared.Columns[0].Cards) != 1 {
+		t.Errorf("expected the owner to see their own project board, got %d %+v", status, shared)
+	}
+
+	// Links to clients deleted later do not block saving the project
+	h.Store.Delete(db.SystemPersona, db.AppID, db.ClientKeyPrefix+"alice")
+	if status := do("owner", "PUT", "/api/projects/p1", `{"name": "One!", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}, {"id": "u2", "nickname": "bob"}]}`, &resp); status != http.StatusOK {
+		t.Errorf("expected existing links to be kept, got %d %+v", status, resp.Error)
+	}
+}
+
+func TestMyCards(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", client)
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"owner", "alice"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do("owner", "POST", "/api/projects", `{"id": "p1", "name": "Shared", "tag": "shared", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}, {"id": "u2", "nickname": "bob"}]}`, nil)
+	do("owner", "POST", "/api/projects", `{"id": "p2", "name": "Private", "tag": "private"}`, nil)
+	do("owner", "POST", "/api/kanban", `{"columns": [
+		{"id": "todo", "title": "To do", "purpose": "todo", "cards": [
+			{"id": "o1", "title": "Mine", "createdAt": 1, "projectId": "p1", "assignee": "ali", "priority": "high", "dueDate": "2030-01-05"},
+			{"id": "o2", "title": "Bob's", "createdAt": 2, "projectId": "p1", "assignee": "bob", "priority": "low", "dueDate": "2030-01-01T09:00:00Z"},
+			{"id": "o3", "title": "Hidden", "createdAt": 3, "projectId": "p2", "priority": "high"}]},
+		{"id": "done", "title": "Done", "purpose": "done", "cards": [
+			{"id": "o4", "title": "Finished", "createdAt": 4, "projectId": "p1", "assignee": "ali", "dueDate": "2029-12-31"}]}]}`, nil)
+	do("alice", "POST", "/api/kanban", `{"columns": [{"id": "inbox", "title": "Inbox", "cards": [
+		{"id": "a1", "title": "Own", "createdAt": 5, "priority": "urgent", "dueDate": "2030-01-03"}]}]}`, nil)
+
+	ids := func(query string) string {
+		var cards []db.BoardCard
+		if status := do("alice", "GET", "/api/me/cards"+query, "", &cards); status != http.StatusOK {
+			t.Fatalf("GET /me/cards%s: %d", query, status)
+		}
+		var out []string
+		for _, card := range cards {
+			out = append(out, card.Card.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	// Own cards and the shared project's cards, never other projects
+	if got := ids(""); got != "o4,o2,a1,o1" {
+		t.Errorf("expected every accessible card soonest first, got %s", got)
+	}
+	for query, want := range map[string]string{
+		"?assignee=me":          "o4,o1",
+		"?assignee=BOB":         "o2",
+		"?assignee=none":        "a1",
+		"?priority=high,urgent": "a1,o1",
+		"?due_after=2030-01-01&due_before=2030-01-03":    "o2,a1",
+		"?project=p1&purpose=todo":                       "o2,o1",
+		"?purpose=none":                                  "a1",
+		"?assignee=me&purpose=todo,in-progress&project=": "o1",
+	} {
+		if got := ids(query); got != want {
+			t.Errorf("%s: expected %s, got %s", query, want, got)
+		}
+	}
+
+	var resp ErrorResponse
+	if status := do("alice", "GET", "/api/me/cards?due_before=soon", "", &resp); status != http.StatusBadRequest || resp.Error.Code != CodeValidation {
+		t.Errorf("expected an invalid date to be rejected, got %d %+v", status, resp.Error)
+	}
+
+	var work []db.BoardCard
+	if status := do("alice", "GET", "/api/me/work", "", &work); status != http.StatusOK || len(work) != 2 {
+		t.Errorf("expected /me/work to match assignee=me, got %d %+v", status, work)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", client)
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"owner", "alice"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do("owner", "POST", "/api/projects", `{"id": "p1", "name": "Launch rocket", "tag": "launch", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}]}`, nil)
+	do("owner", "POST", "/api/projects", `{"id": "p2", "name": "Secret launch", "tag": "secret"}`, nil)
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [
+		{"id": "c1", "title": "Fuel the rocket", "createdAt": 1, "projectId": "p1", "description": "Check the <valves> first"},
+		{"id": "c2", "title": "Hidden rocket", "createdAt": 2, "projectId": "p2"},
+		{"id": "c3", "title": "Countdown", "createdAt": 3, "checklist": [{"id": "i1", "text": "Rocket on pad", "completed": false}]}]}]}`, nil)
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "f1", OriginalName: "rocket-specs.pdf", OwnerID: "owner"})
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "f2", OriginalName: "rocket-poster.png", OwnerID: "owner", IsPublic: true})
+
+	ids := func(client, query string) string {
+		var resp SearchResponse
+		if status := do(client, "GET", "/api/search?"+query, "", &resp); status != http.StatusOK {
+			t.Fatalf("search %s: %d", query, status)
+		}
+		var out []string
+		for _, r := range resp.Results {
+			out = append(out, r.Kind+":"+r.ID)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	if got := ids("owner", "q=rocket"); got != "card:c1,card:c2,card:c3,file:f1,file:f2,project:p1" {
+		t.Errorf("owner: unexpected results %s", got)
+	}
+	// Members see the shared project and its cards, plus public files
+	if got := ids("alice", "q=rocket"); got != "card:c1,file:f2,project:p1" {
+		t.Errorf("alice: unexpected results %s", got)
+	}
+	if got := ids("owner", "q=launch&kind=project"); got != "project:p1,project:p2" {
+		t.Errorf("kind filter: unexpected results %s", got)
+	}
+
+	var resp SearchResponse
+	do("owner", "GET", "/api/search?q=valv", "", &resp)
+	if len(resp.Results) != 1 || resp.Results[0].Highlights["description"] != "Check the &lt;<mark>valves</mark>&gt; first" {
+		t.Errorf("expected an escaped highlight, got %+v", resp.Results)
+	}
+
+	// Writes keep the index current
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [
+		{"id": "c1", "title": "Fuel the shuttle", "createdAt": 1, "projectId": "p1"}]}]}`, nil)
+	if got := ids("owner", "q=rocket&kind=card"); got != "" {
+		t.Errorf("expected removed and renamed cards to drop out, got %s", got)
+	}
+	if got := ids("owner", "q=shuttle"); got != "card:c1" {
+		t.Errorf("expected the renamed card, got %s", got)
+	}
+	db.DeleteFileRecord(h.Store, "f1")
+	if got := ids("owner", "q=specs"); got != "" {
+		t.Errorf("expected the deleted file to drop out, got %s", got)
+	}
+
+	// A rebuilt index finds the same documents
+	before := h.Index.Len()
+	if err := h.Index.Rebuild(h.Store); err != nil || h.Index.Len() != before {
+		t.Errorf("expected rebuild to restore %d documents, got %d (%v)", before, h.Index.Len(), err)
+	}
+
+	if status := do("owner", "GET", "/api/search?q=+", "", nil); status != http.StatusBadRequest {
+		t.Errorf("expected an empty query to be rejected, got %d", status)
+	}
+	if status := do("owner", "GET", "/api/search?q=x&kind=user", "", nil); status != http.StatusBadRequest {
+		t.Errorf("expected an unknown kind to be rejected, got %d", status)
+	}
+}
+
+func TestDueDateNotifications(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", client)
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"owner", "alice"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	do("owner", "POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}]}`, nil)
+	board := func(c5Due string) string {
+		return `{"columns": [
+			{"id": "todo", "title": "To do", "purpose": "todo", "cards": [
+				{"id": "c1", "title": "Today", "createdAt": 1, "dueDate": "2030-01-01"},
+				{"id": "c2", "title": "Late", "createdAt": 2, "dueDate": "2029-12-31"},
+				{"id": "c3", "title": "Later", "createdAt": 3, "dueDate": "2030-01-10"},
+				{"id": "c5", "title": "Alice's", "createdAt": 5, "projectId": "p1", "assignee": "ali", "dueDate": "` + c5Due + `"}]},
+			{"id": "done", "title": "Done", "purpose": "done", "cards": [
+				{"id": "c4", "title": "Finished", "createdAt": 4, "dueDate": "2029-12-01"}]}]}`
+	}
+	do("owner", "POST", "/api/kanban", board("2030-01-01T18:00:00Z"), nil)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	if n, err := h.CheckDueDates(now); err != nil || n != 4 {
+		t.Fatalf("expected 3 notifications for the owner and 1 for alice, got %d (%v)", n, err)
+	}
+	if n, _ := h.CheckDueDates(now.Add(time.Minute)); n != 0 {
+		t.Errorf("expected no repeated notifications, got %d", n)
+	}
+
+	var list NotificationList
+	do("owner", "GET", "/api/notifications", "", &list)
+	kinds := map[string]string{}
+	for _, n := range list.Notifications {
+		kinds[n.CardID] = n.Type
+	}
+	if list.Unread != 3 || kinds["c1"] != db.NotificationDueSoon || kinds["c2"] != db.NotificationOverdue || kinds["c5"] != db.NotificationDueSoon {
+		t.Errorf("unexpected owner notifications %+v", list)
+	}
+
+	// Alice's open stream gets the overdue notice of her card
+	req, _ := http.NewRequest("GET", srv.URL+"/api/events?client_id=alice", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	if line := <-lines; line != ": connected" {
+		t.Fatalf("unexpected first line %q", line)
+	}
+
+	if n, _ := h.CheckDueDates(now.Add(7 * time.Hour)); n != 2 {
+		t.Errorf("expected the overdue card to notify owner and alice, got %d", n)
+	}
+	var event, data string
+	for line := range lines {
+		if strings.HasPrefix(line, "event:") {
+			event = line
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = line
+			break
+		}
+	}
+	if event != "event:notification" || !strings.Contains(data, `"card.overdue"`) || !strings.Contains(data, `"c5"`) {
+		t.Errorf("unexpected event %q %q", event, data)
+	}
+
+	// Alice also heard about the card being assigned to her
+	do("alice", "GET", "/api/notifications?unread=true", "", &list)
+	if len(list.Notifications) != 3 || list.Unread != 3 {
+		t.Fatalf("expected three unread notifications for alice, got %+v", list)
+	}
+	var marked MarkReadResponse
+	do("alice", "POST", "/api/notifications/read", `{"ids": ["`+list.Notifications[0].ID+`"]}`, &marked)
+	do("alice", "GET", "/api/notifications", "", &list)
+	if marked.Marked != 1 || list.Unread != 2 || len(list.Notifications) != 3 {
+		t.Errorf("expected one notification marked read, got %d %+v", marked.Marked, list)
+	}
+	do("alice", "POST", "/api/notifications/read", `{}`, &marked)
+	if marked.Marked != 2 {
+		t.Errorf("expected the rest to be marked read, got %d", marked.Marked)
+	}
+
+	// More overdue cards than notifications are kept are still reminded once
+	cards := make([]db.KanbanCard, 250)
+	for i := range cards {
+		cards[i] = db.KanbanCard{ID: fmt.Sprintf("b%d", i), Title: "Backlog", CreatedAt: 1, DueDate: "2029-06-01"}
+	}
+	busy, _ := json.Marshal(db.KanbanBoard{Columns: []db.KanbanColumn{{ID: "todo", Title: "To do", Cards: cards}}})
+	do("busy", "POST", "/api/kanban", string(busy), nil)
+	later := now.Add(8 * time.Hour)
+	if n, _ := h.CheckDueDates(later); n != len(cards) {
+		t.Errorf("expected %d reminders for the busy board, got %d", len(cards), n)
+	}
+	if n, _ := h.CheckDueDates(later.Add(time.Minute)); n != 0 {
+		t.Errorf("expected no repeated reminders past the notification cap, got %d", n)
+	}
+
+	// A trashed client's board reminds nobody
+	db.UpsertClient(h.Store, "busy", "busy", "code-busy", 1)
+	cards[0].DueDate = "2029-07-01"
+	busy, _ = json.Marshal(db.KanbanBoard{Columns: []db.KanbanColumn{{ID: "todo", Title: "To do", Cards: cards}}})
+	do("busy", "POST", "/api/kanban", string(busy), nil)
+	db.TrashClient(h.Store, "busy", 2)
+	if n, _ := h.CheckDueDates(later.Add(2 * time.Minute)); n != 0 {
+		t.Errorf("expected no reminders for a trashed client, got %d", n)
+	}
+
+	// Closing the bus ends open streams
+	h.Events.Close()
+	for range lines {
+	}
+}
+
+func TestEmailNotifications(t *testing.T) {
+	h, storageDir, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	smtp, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer smtp.Close()
+	h.Mailer = &mail.SMTP{Addr: smtp.Addr, From: "flow@example.com", TLS: mail.TLSNone}
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if client != "" {
+			req.Header.Set("X-Client-ID", client)
+		}
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"owner", "alice"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything is off until opted into, which needs an address
+	var prefs db.EmailPrefs
+	if code := do("alice", "GET", "/api/persona/email", "", &prefs); code != http.StatusOK || prefs != (db.EmailPrefs{}) {
+		t.Fatalf("expected empty preferences, got %d %+v", code, prefs)
+	}
+	if code := do("alice", "PUT", "/api/persona/email", `{"assignments": true}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without an address, got %d", code)
+	}
+	if code := do("alice", "PUT", "/api/persona/email", `{"email": "not an address", "assignments": true}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad address, got %d", code)
+	}
+	do("alice", "PUT", "/api/persona/email", `{"email": " alice@example.com ", "assignments": true}`, &prefs)
+	if prefs.Email != "alice@example.com" || !prefs.Assignments || prefs.DueReminders {
+		t.Errorf("unexpected preferences %+v", prefs)
+	}
+	do("owner", "PUT", "/api/persona/email", `{"email": "owner@example.com", "share_links": true}`, nil)
+
+	// Assigning a card to a user linked to alice notifies her once
+	do("owner", "POST", "/api/projects", `{"id": "p1", "name": "One", "tag": "one", "users": [{"id": "u1", "nickname": "ali", "clientId": "alice"}]}`, nil)
+	board := `{"columns": [{"id": "todo", "title": "To do", "cards": [
+		{"id": "c1", "title": "Review <draft>", "createdAt": 1, "projectId": "p1", "assignee": "ali", "dueDate": "2030-01-01"}]}]}`
+	for range 2 {
+		if code := do("owner", "POST", "/api/kanban", board, nil); code != http.StatusOK {
+			t.Fatalf("expected the board to save, got %d", code)
+		}
+	}
+	var list NotificationList
+	do("alice", "GET", "/api/notifications", "", &list)
+	if len(list.Notifications) != 1 || list.Notifications[0].Type != db.NotificationAssigned || list.Notifications[0].CardID != "c1" {
+		t.Fatalf("expected one assignment notification, got %+v", list)
+	}
+
+	// Downloads through the share link by others notify the owner, at most
+	// once an hour; downloads by ID do not
+	path := filepath.Join(storageDir, "f1")
+	os.WriteFile(path, []byte("hello"), 0644)
+	db.SaveFileRecord(h.Store, db.FileRecord{ID: "f1", OriginalName: "report.pdf", StoredPath: path, OwnerID: "owner", DownloadLink: "share-f1", IsPublic: true})
+	for _, req := range []struct{ client, path string }{
+		{"", "/api/download/share-f1"},
+		{"alice", "/api/download/share-f1"},
+		{"owner", "/api/download/share-f1"},
+		{"", "/api/download/f1"},
+	} {
+		if code := do(req.client, "GET", req.path, "", nil); code != http.StatusOK {
+			t.Fatalf("expected %s to download, got %d", req.path, code)
+		}
+	}
+	do("owner", "GET", "/api/notifications", "", &list)
+	if len(list.Notifications) != 1 || list.Notifications[0].Type != db.NotificationDownloaded || list.Notifications[0].FileID != "f1" {
+		t.Fatalf("expected one download notification, got %+v", list)
+	}
+
+	// Only admins see the outbox
+	var outbox OutboxList
+	if code := do("alice", "GET", "/api/admin/outbox", "", nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin, got %d", code)
+	}
+	db.UpdateClientAdminStatus(h.Store, "owner", true)
+	do("owner", "GET", "/api/admin/outbox", "", &outbox)
+	if len(outbox.Entries) != 2 {
+		t.Fatalf("expected two queued emails, got %+v", outbox)
+	}
+
+	// A failed send is retried after a backoff
+	now := time.Now()
+	smtp.Fail(1)
+	if sent, failed, err := h.DeliverMail(context.Background(), now); err != nil || sent != 1 || failed != 1 {
+		t.Fatalf("expected one sent and one failed, got %d %d (%v)", sent, failed, err)
+	}
+	do("owner", "GET", "/api/admin/outbox", "", &outbox)
+	if len(outbox.Entries) != 1 || outbox.Entries[0].Attempts != 1 || outbox.Entries[0].LastError == "" {
+		t.Fatalf("expected the failed email to wait for a retry, got %+v", outbox)
+	}
+	if sent, _, _ := h.DeliverMail(context.Background(), now); sent != 0 {
+		t.Errorf("expected no retry before the backoff, sent %d", sent)
+	}
+	if sent, _, _ := h.DeliverMail(context.Background(), now.Add(2*time.Minute)); sent != 1 {
+		t.Errorf("expected the retry to be sent, sent %d", sent)
+	}
+
+	messages := smtp.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected two delivered emails, got %d", len(messages))
+	}
+	to := map[string]string{}
+	for _, m := range messages {
+		to[m.To[0]] = m.Data
+	}
+	if !strings.Contains(to["alice@example.com"], "Assigned to you: Review <draft>") || !strings.Contains(to["alice@example.com"], "Review &lt;draft&gt;") {
+		t.Errorf("unexpected assignment email %q", to["alice@example.com"])
+	}
+	if !strings.Contains(to["owner@example.com"], "report.pdf") {
+		t.Errorf("unexpected share link email %q", to["owner@example.com"])
+	}
+
+	// Emails are given up on after MaxMailAttempts
+	smtp.Fail(db.MaxMailAttempts)
+	do("owner", "POST", "/api/kanban", `{"columns": []}`, nil)
+	do("owner", "POST", "/api/kanban", board, nil)
+	for i := range db.MaxMailAttempts {
+		h.DeliverMail(context.Background(), now.Add(time.Duration(i+1)*7*time.Hour))
+	}
+	do("owner", "GET", "/api/admin/outbox", "", &outbox)
+	if len(outbox.Entries) != 1 || outbox.Entries[0].Status != db.OutboxFailed || outbox.Entries[0].Attempts != db.MaxMailAttempts {
+		t.Errorf("expected a failed email, got %+v", outbox)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+	// The receiver listens on loopback, which client webhooks may not reach
+	h.Webhooks = &webhook.Sender{AllowPrivate: true}
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var mu sync.Mutex
+	var got []received
+	failing := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		got = append(got, received{r.Header, body})
+	}))
+	defer receiver.Close()
+	take := func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		out := got
+		got = nil
+		return out
+	}
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+	do := func(client, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Client-ID", client)
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	for _, id := range []string{"admin", "owner", "other"} {
+		if err := db.UpsertClient(h.Store, id, id, "code-"+id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.UpdateClientAdminStatus(h.Store, "admin", true)
+
+	if code := do("owner", "POST", "/api/webhooks", `{"url": "ftp://example.com", "events": ["card.created"]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-http URL, got %d", code)
+	}
+	if code := do("owner", "POST", "/api/webhooks", `{"url": "`+receiver.URL+`", "events": ["card.exploded"]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown event, got %d", code)
+	}
+	var own db.Webhook
+	if code := do("owner", "POST", "/api/webhooks", `{"url": "`+receiver.URL+`/own", "events": ["card.moved", "card.created", "file.uploaded", "card.created"]}`, &own); code != http.StatusCreated {
+		t.Fatalf("expected the webhook to be created, got %d", code)
+	}
+	if own.Owner != "owner" || len(own.Secret) != 64 || !slices.Equal(own.Events, []string{"card.created", "card.moved", "file.uploaded"}) {
+		t.Errorf("unexpected webhook %+v", own)
+	}
+	if code := do("other", "GET", "/api/webhooks/"+own.ID, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another client's webhook, got %d", code)
+	}
+	if code := do("admin", "GET", "/api/webhooks/"+own.ID, "", nil); code != http.StatusOK {
+		t.Errorf("expected admins to see any webhook, got %d", code)
+	}
+	if code := do("owner", "POST", "/api/admin/webhooks", `{"url": "`+receiver.URL+`", "events": ["card.created"]}`, nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 for an instance-wide webhook of a non-admin, got %d", code)
+	}
+	var global db.Webhook
+	do("admin", "POST", "/api/admin/webhooks", `{"url": "`+receiver.URL+`/global", "events": ["card.created", "client.deleted"], "secret": "global-secret"}`, &global)
+	var list WebhookList
+	do("owner", "GET", "/api/webhooks", "", &list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != own.ID {
+		t.Errorf("expected only the caller's webhook, got %+v", list)
+	}
+	do("admin", "GET", "/api/admin/webhooks", "", &list)
+	if len(list.Webhooks) != 2 {
+		t.Errorf("expected both webhooks for the admin, got %+v", list)
+	}
+
+	// Creating a card reaches the owner's and the instance-wide webhook,
+	// moving it only the owner's; other clients' cards only the latter
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [{"id": "c1", "title": "One", "createdAt": 1}]}, {"id": "done", "title": "Done", "cards": []}]}`, nil)
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": []}, {"id": "done", "title": "Done", "cards": [{"id": "c1", "title": "One", "createdAt": 1}]}]}`, nil)
+	do("other", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [{"id": "x1", "title": "Other", "createdAt": 1}]}]}`, nil)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "report.txt")
+	part.Write([]byte("hello"))
+	writer.Close()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Client-ID", "owner")
+	router.ServeHTTP(w, req)
+
+	now := time.Now()
+	if delivered, failed, err := h.DeliverWebhooks(context.Background(), now); err != nil || delivered != 5 || failed != 0 {
+		t.Fatalf("expected 5 deliveries, got %d delivered, %d failed (%v)", delivered, failed, err)
+	}
+	if global.Secret != "global-secret" {
+		t.Errorf("expected the given secret to be kept, got %q", global.Secret)
+	}
+	events := map[string][]string{}
+	for _, r := range take() {
+		var e db.WebhookEvent
+		json.Unmarshal(r.body, &e)
+		if r.header.Get(webhook.HeaderEvent) != e.Type {
+			t.Errorf("event header %q does not match payload %q", r.header.Get(webhook.HeaderEvent), e.Type)
+		}
+		signature := r.header.Get(webhook.HeaderSignature)
+		if webhook.Verify(own.Secret, signature, r.body, time.Now(), time.Minute) != nil &&
+			webhook.Verify(global.Secret, signature, r.body, time.Now(), time.Minute) != nil {
+			t.Errorf("unverifiable signature on %s", e.Type)
+		}
+		events[e.Owner] = append(events[e.Owner], e.Type)
+	}
+	ownerHandle, otherHandle := db.OwnerHandle("owner"), db.OwnerHandle("other")
+	sort.Strings(events[ownerHandle])
+	if !slices.Equal(events[ownerHandle], []string{"card.created", "card.created", "card.moved", "file.uploaded"}) || !slices.Equal(events[otherHandle], []string{"card.created"}) {
+		t.Errorf("unexpected deliveries %v", events)
+	}
+
+	// Failed deliveries are retried with a backoff and logged
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": []}, {"id": "done", "title": "Done", "cards": [{"id": "c1", "title": "One", "createdAt": 1}, {"id": "c2", "title": "Two", "createdAt": 2}]}]}`, nil)
+	if _, failed, _ := h.DeliverWebhooks(context.Background(), now); failed != 2 {
+		t.Fatalf("expected both deliveries to fail, got %d", failed)
+	}
+	var log DeliveryList
+	do("owner", "GET", "/api/webhooks/"+own.ID+"/deliveries", "", &log)
+	pending := slices.IndexFunc(log.Deliveries, func(d db.WebhookDelivery) bool { return d.Status == db.DeliveryPending })
+	if len(log.Deliveries) != 4 || pending < 0 || log.Deliveries[pending].ResponseStatus != http.StatusInternalServerError || log.Deliveries[pending].Attempts != 1 {
+		t.Fatalf("expected three delivered and one pending delivery in the log, got %+v", log.Deliveries)
+	}
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if delivered, _, _ := h.DeliverWebhooks(context.Background(), now); delivered != 0 {
+		t.Errorf("expected no retry before the backoff, got %d", delivered)
+	}
+	if delivered, _, _ := h.DeliverWebhooks(context.Background(), now.Add(time.Minute)); delivered != 2 {
+		t.Errorf("expected both retries to be delivered, got %d", delivered)
+	}
+	take()
+
+	// Disabled webhooks get nothing; deleting a client reaches the
+	// instance-wide webhook
+	do("owner", "PUT", "/api/webhooks/"+own.ID, `{"url": "`+receiver.URL+`/own", "events": ["card.created"], "disabled": true}`, &own)
+	if !own.Disabled || own.Secret == "" {
+		t.Errorf("expected a disabled webhook that kept its secret, got %+v", own)
+	}
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [{"id": "c3", "title": "Three", "createdAt": 3}]}]}`, nil)
+	if code := do("admin", "DELETE", "/api/clients/other", "", nil); code != http.StatusOK {
+		t.Fatalf("expected the client to be deleted, got %d", code)
+	}
+	h.DeliverWebhooks(context.Background(), now)
+	var types []string
+	for _, r := range take() {
+		var e struct {
+			Type string             `json:"type"`
+			Data ClientDeletedEvent `json:"data"`
+		}
+		json.Unmarshal(r.body, &e)
+		types = append(types, e.Type)
+		if e.Type == db.EventClientDeleted && (e.Data.ID != otherHandle || e.Data.Mode != "cascade") {
+			t.Errorf("unexpected client.deleted data %+v", e.Data)
+		}
+	}
+	sort.Strings(types)
+	if !slices.Equal(types, []string{"card.created", "client.deleted"}) {
+		t.Errorf("expected the instance-wide webhook only, got %v", types)
+	}
+
+	if code := do("owner", "DELETE", "/api/webhooks/"+own.ID, "", nil); code != http.StatusOK {
+		t.Errorf("expected the webhook to be deleted, got %d", code)
+	}
+	if deliveries, _ := db.ListDeliveries(h.Store, own.ID); len(deliveries) != 0 {
+		t.Errorf("expected the delivery log to go with the webhook, got %d", len(deliveries))
+	}
+
+	// Only instance-wide webhooks may reach private addresses
+	h.Webhooks = &webhook.Sender{}
+	var internal db.Webhook
+	do("owner", "POST", "/api/webhooks", `{"url": "`+receiver.URL+`/internal", "events": ["card.created"]}`, &internal)
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [{"id": "c4", "title": "Four", "createdAt": 4}]}]}`, nil)
+	if delivered, failed, _ := h.DeliverWebhooks(context.Background(), now); delivered != 1 || failed != 1 {
+		t.Errorf("expected the instance-wide delivery only, got %d delivered, %d failed", delivered, failed)
+	}
+	var refused DeliveryList
+	do("owner", "GET", "/api/webhooks/"+internal.ID+"/deliveries", "", &refused)
+	if len(refused.Deliveries) != 1 || refused.Deliveries[0].ResponseStatus != 0 || !strings.Contains(refused.Deliveries[0].LastError, "not a public address") {
+		t.Errorf("expected the private destination to be refused before connecting, got %+v", refused.Deliveries)
+	}
+	for _, r := range take() {
+		if r.header.Get(webhook.HeaderEvent) != db.EventCardCreated {
+			t.Errorf("unexpected delivery %s", r.header.Get(webhook.HeaderEvent))
+		}
+	}
+}
+
+func TestInboundHooks(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+	do := func(client, method, path, body string, header http.Header, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if client != "" {
+			req.Header.Set("X-Client-ID", client)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	if err := db.UpsertClient(h.Store, "owner", "owner", "code-owner", 1); err != nil {
+		t.Fatal(err)
+	}
+	do("owner", "POST", "/api/kanban", `{"columns": [{"id": "todo", "title": "To do", "cards": [{"id": "c1", "title": "One", "createdAt": 1}]}, {"id": "bugs", "title": "Bugs", "cards": []}]}`, nil, nil)
+	stream, cancel := h.Events.Subscribe("owner")
+	defer cancel()
+
+	if code := do("owner", "POST", "/api/inbound-hooks", `{"name": " "}`, nil, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a name, got %d", code)
+	}
+	var hook InboundHook
+	if code := do("owner", "POST", "/api/inbound-hooks", `{"name": "Alerts", "column": "bugs"}`, nil, &hook); code != http.StatusCreated {
+		t.Fatalf("expected the hook to be created, got %d", code)
+	}
+	if hook.Token == "" || hook.Path != "/api/v1/hooks/"+hook.ID {
+		t.Errorf("unexpected hook %+v", hook)
+	}
+	var list InboundHookList
+	do("owner", "GET", "/api/inbound-hooks", "", nil, &list)
+	if len(list.Hooks) != 1 || list.Hooks[0].Token != "" {
+		t.Errorf("expected the hook without its token, got %+v", list)
+	}
+
+	token := http.Header{"X-Flow-Token": {hook.Token}}
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Nope"}`, http.Header{"X-Flow-Token": {"wrong"}}, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a wrong token, got %d", code)
+	}
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Nope"}`, nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 without a token, got %d", code)
+	}
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Nope", "priority": "someday"}`, token, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown priority, got %d", code)
+	}
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Nope", "column": "Later"}`, token, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown column, got %d", code)
+	}
+
+	var change db.CardChange
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": " Disk full ", "description": "on db-1", "priority": "Urgent"}`, token, &change); code != http.StatusCreated {
+		t.Fatalf("expected the card to be added, got %d", code)
+	}
+	if change.ColumnID != "bugs" || change.Card.Title != "Disk full" || change.Card.Priority != "urgent" || change.Card.ID == "" {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if code := do("", "POST", "/api/hooks/"+hook.ID+"?token="+hook.Token, `{"title": "Follow up", "column": "to do"}`, nil, &change); code != http.StatusCreated || change.ColumnID != "todo" {
+		t.Errorf("expected the card in the column matched by title, got %d %+v", code, change)
+	}
+	select {
+	case e := <-stream:
+		if e.Type != EventKanbanChanged {
+			t.Errorf("expected a kanban.changed event, got %s", e.Type)
+		}
+	default:
+		t.Error("expected an event for the owner")
+	}
+
+	board, _, err := db.GetKanban(h.Store, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board.Columns[0].Cards) != 2 || len(board.Columns[1].Cards) != 1 || board.Columns[1].Cards[0].Version != db.KanbanCardVersion {
+		t.Errorf("unexpected board %+v", board)
+	}
+	do("owner", "GET", "/api/inbound-hooks", "", nil, &list)
+	if list.Hooks[0].LastUsedAt == 0 {
+		t.Error("expected the hook's last use to be recorded")
+	}
+
+	// Trashing the owner suspends its hooks, restoring brings them back
+	db.TrashClient(h.Store, "owner", 2)
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Ghost"}`, token, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a hook of a trashed client, got %d", code)
+	}
+	db.RestoreClient(h.Store, "owner")
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Back"}`, token, nil); code != http.StatusCreated {
+		t.Errorf("expected the hook to work again after a restore, got %d", code)
+	}
+
+	if code := do("other", "DELETE", "/api/inbound-hooks/"+hook.ID, "", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another client's hook, got %d", code)
+	}
+	// Deliveries racing the revocation must not write the hook back
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Racing"}`, token, nil)
+		}()
+	}
+	if code := do("owner", "DELETE", "/api/inbound-hooks/"+hook.ID, "", nil, nil); code != http.StatusOK {
+		t.Errorf("expected the hook to be revoked, got %d", code)
+	}
+	wg.Wait()
+	if _, err := db.GetInboundHook(h.Store, hook.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the revoked hook to stay deleted, got %v", err)
+	}
+	if code := do("", "POST", "/api/hooks/"+hook.ID, `{"title": "Late"}`, token, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a revoked hook, got %d", code)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	h, _, cleanup := setupTestHandler(t)
+	defer cleanup()
+
+	router := gin.New()
+	h.RegisterRoutes(router.Group("/api"))
+	do := func(client, token, method, path, body string, out any) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if client != "" {
+			req.Header.Set("X-Client-ID", client)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		if out != nil {
+			json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+	if err := db.UpsertClient(h.Store, "owner", "owner", "code-owner", 1); err != nil {
+		t.Fatal(err)
+	}
+	db.UpdateClientAdminStatus(h.Store, "owner", true)
+	do("owner", "", "POST", "/api/store/settings", `{"theme": "dark"}`, nil)
+
+	if code := do("owner", "", "POST", "/api/persona/tokens", `{"name": "ci", "scope": "admin"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown scope, got %d", code)
+	}
+	tokens := map[string]APIToken{}
+	for _, scope := range db.TokenScopes {
+		var created APIToken
+		if code := do("owner", "", "POST", "/api/persona/tokens", `{"name": "`+scope+` script", "scope": "`+scope+`"}`, &created); code != http.StatusCreated {
+			t.Fatalf("expected a %s token, got %d", scope, code)
+		}
+		if !strings.HasPrefix(created.Token, "flow_"+created.ID+"_") {
+			t.Errorf("unexpected token %q", created.Token)
+		}
+		tokens[scope] = created
+	}
+	readOnly, readWrite, files := tokens[db.ScopeReadOnly].Token, tokens[db.ScopeReadWrite].Token, tokens[db.ScopeFiles].Token
+
+	// Tokens act for their owner, whatever X-Client-ID says
+	var val map[string]any
+	if code := do("intruder", readOnly, "GET", "/api/store/settings", "", &val); code != http.StatusOK || val["theme"] != "dark" {
+		t.Errorf("expected the owner's value, got %d %v", code, val)
+	}
+	if code := do("", "not-a-token", "GET", "/api/store/settings", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a malformed token, got %d", code)
+	}
+	if code := do("", readOnly+"x", "GET", "/api/store/settings", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong secret, got %d", code)
+	}
+
+	if code := do("", readOnly, "POST", "/api/store/settings", `{"theme": "light"}`, nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 for a write with a read-only token, got %d", code)
+	}
+	if code := do("", readWrite, "POST", "/api/store/settings", `{"theme": "light"}`, nil); code != http.StatusOK {
+		t.Errorf("expected a read-write token to write, got %d", code)
+	}
+	if code := do("", files, "GET", "/api/store/settings", "", nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 outside files for a files-only token, got %d", code)
+	}
+	if code := do("", files, "GET", "/api/files", "", nil); code != http.StatusOK {
+		t.Errorf("expected a files-only token to list files, got %d", code)
+	}
+	// The persona routes hand out the recovery code and manage tokens;
+	// admin rights never pass to tokens
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/api/persona"},
+		{"GET", "/api/persona/export"},
+		{"POST", "/api/persona/tokens"},
+		{"GET", "/api/admin/backup"},
+		{"GET", "/api/admin/outbox"},
+		{"GET", "/api/clients"},
+	} {
+		if code := do("", readWrite, route.method, route.path, `{"name": "more", "scope": "read-write"}`, nil); code != http.StatusForbidden {
+			t.Errorf("expected 403 for %s %s with a token, got %d", route.method, route.path, code)
+		}
+	}
+
+	var list APITokenList
+	do("owner", "", "GET", "/api/persona/tokens", "", &list)
+	if len(list.Tokens) != 3 {
+		t.Fatalf("expected 3 tokens, got %+v", list)
+	}
+	for _, listed := range list.Tokens {
+		if listed.Token != "" || listed.LastUsedAt == 0 {
+			t.Errorf("expected a used token listed without its token, got %+v", listed)
+		}
+	}
+
+	if code := do("intruder", "", "DELETE", "/api/persona/tokens/"+tokens[db.ScopeReadWrite].ID, "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for another client's token, got %d", code)
+	}
+	if code := do("owner", "", "DELETE", "/api/persona/tokens/"+tokens[db.ScopeReadWrite].ID, "", nil); code != http.StatusOK {
+		t.Errorf("expected the token to be revoked, got %d", code)
+	}
+	if code := do("", readWrite, "GET", "/api/store/settings", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a revoked token, got %d", code)
+	}
+
+	// Trashing the owner suspends its tokens, restoring brings them back
+	db.TrashClient(h.Store, "owner", 2)
+	if code := do("", readOnly, "GET", "/api/store/settings", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a token of a trashed client, got %d", code)
+	}
+	db.RestoreClient(h.Store, "owner")
+	if code := do("", readOnly, "GET", "/api/store/settings", "", nil); code != http.StatusOK {
+		t.Errorf("expected the token to work again after a restore, got %d", code)
+	}
+}
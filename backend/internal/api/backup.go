@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/backup"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportBackup streams a full instance snapshot as tar.gz.
+func (h *Handler) ExportBackup(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	name := fmt.Sprintf("flow-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged; the
+	// truncated archive will not pass validation on restore.
+	if _, err := backup.Export(c.Writer, h.Store, h.appVersion()); err != nil {
+		logger(c).Error("backup export failed", "err", err)
+	}
+}
+
+// RestoreBackup replaces all data with the archive uploaded as "file".
+func (h *Handler) RestoreBackup(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		badRequest(c, "No file is received")
+		return
+	}
+	defer file.Close()
+
+	manifest, err := backup.Restore(file, h.Store, h.StorageDir, h.appVersion())
+	if err != nil {
+		respondError(c, err, "Failed to restore backup")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "manifest": manifest})
+}
+
+// ExportPersona streams a takeout archive with all data of the caller.
+func (h *Handler) ExportPersona(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	name := fmt.Sprintf("flow-export-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Status(http.StatusOK)
+
+	if _, err := backup.ExportPersona(c.Writer, h.Store, ownerID, h.appVersion()); err != nil {
+		logger(c).Error("persona export failed", "err", err)
+	}
+}
+
+// ImportPersona loads a takeout archive uploaded as "file" into the caller's
+// persona. mode=merge (default) keeps existing data, mode=replace drops it.
+func (h *Handler) ImportPersona(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	mode := c.DefaultQuery("mode", "merge")
+	if mode != "merge" && mode != "replace" {
+		badRequest(c, "mode must be merge or replace")
+		return
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		badRequest(c, "No file is received")
+		return
+	}
+	defer file.Close()
+
+	manifest, err := backup.ImportPersona(file, h.Store, h.StorageDir, ownerID, h.appVersion(), mode == "replace")
+	if err != nil {
+		respondError(c, err, "Failed to import data")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "manifest": manifest})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Prefix is the unversioned API prefix, kept as an alias of V1Prefix.
+	Prefix = "/api"
+	// V1Prefix is the canonical prefix of the current API version.
+	V1Prefix = "/api/v1"
+)
+
+// aliasDeprecatedSince is when /api/v1 became canonical.
+var aliasDeprecatedSince = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// Deprecation describes a deprecated route or group of routes. Responses
+// carry the Deprecation (RFC 9745) and Sunset (RFC 8594) headers and link to
+// the successor, so clients can migrate before the route goes away.
+//
+// A changed payload gets a new route or version group; the old one stays and
+// is marked with Deprecated until its sunset.
+type Deprecation struct {
+	Since time.Time
+	// Sunset is when the route stops working; zero while undecided. From
+	// then on requests are answered with 410 Gone.
+	Sunset time.Time
+	// Successor is the path of the route replacing this one.
+	Successor string
+}
+
+// Deprecated marks the routes it is used on as deprecated.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	return deprecated(d, func(*gin.Context) string { return d.Successor })
+}
+
+// Alias marks a group mounted at prefix as a deprecated alias of the same
+// routes under canonical; each response links to its canonical path.
+func Alias(prefix, canonical string, d Deprecation) gin.HandlerFunc {
+	return deprecated(d, func(c *gin.Context) string {
+		return canonical + strings.TrimPrefix(c.Request.URL.Path, prefix)
+	})
+}
+
+func deprecated(d Deprecation, successor func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		link := successor(c)
+		if link != "" {
+			header.Add("Link", "<"+link+`>; rel="successor-version"`)
+		}
+		if !d.Sunset.IsZero() {
+			header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			if !time.Now().Before(d.Sunset) {
+				abortWithError(c, http.StatusGone, CodeGone,
+					"This endpoint was retired on "+d.Sunset.Format(time.DateOnly),
+					gin.H{"successor": link})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Mount registers the API under V1Prefix and, as a deprecated alias sunset
+// at AliasSunset, under Prefix.
+func (h *Handler) Mount(r gin.IRouter) {
+	h.RegisterRoutes(r.Group(V1Prefix))
+	h.RegisterRoutes(r.Group(Prefix, Alias(Prefix, V1Prefix, Deprecation{
+		Since:  aliasDeprecatedSince,
+		Sunset: h.AliasSunset,
+	})))
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes of the error envelope. Clients should branch on the code, the
+// message is meant for humans and may change.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeValidation   = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeGone         = "gone"
+	CodeInternal     = "internal_error"
+)
+
+// APIError is the body of every error response:
+//
+//	{"error": {"code": "not_found", "message": "File not found", "details": ...}}
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+func abortWithError(c *gin.Context, status int, code, message string, details any) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: APIError{Code: code, Message: message, Details: details}})
+}
+
+func badRequest(c *gin.Context, message string) {
+	abortWithError(c, http.StatusBadRequest, CodeBadRequest, message, nil)
+}
+
+func unauthorized(c *gin.Context, message string) {
+	abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, message, nil)
+}
+
+func forbidden(c *gin.Context, message string) {
+	abortWithError(c, http.StatusForbidden, CodeForbidden, message, nil)
+}
+
+func notFound(c *gin.Context, message string) {
+	abortWithError(c, http.StatusNotFound, CodeNotFound, message, nil)
+}
+
+// internalError logs err with the request and answers without exposing it.
+func internalError(c *gin.Context, message string, err error) {
+	if err != nil {
+		logger(c).Error(message, "err", err)
+	}
+	abortWithError(c, http.StatusInternalServerError, CodeInternal, message, nil)
+}
+
+// RouteNotFound answers API requests no route matched.
+func RouteNotFound(c *gin.Context) {
+	notFound(c, "API route not found")
+}
+
+// Recovered answers a request whose handler panicked; gin.Recovery has
+// already logged the panic.
+func Recovered(c *gin.Context, _ any) {
+	abortWithError(c, http.StatusInternalServerError, CodeInternal, "Internal server error", nil)
+}
+
+// respondError maps an error of the db error taxonomy to its status and
+// code. Errors of unknown kind become a 500 with the fallback message.
+func respondError(c *gin.Context, err error, fallback string) {
+	var typed *db.Error
+	message := fallback
+	var details any
+	if errors.As(err, &typed) {
+		message = typed.Message
+		details = typed.Details
+	}
+
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		abortWithError(c, http.StatusNotFound, CodeNotFound, message, details)
+	case errors.Is(err, db.ErrValidation):
+		abortWithError(c, http.StatusBadRequest, CodeValidation, message, details)
+	case errors.Is(err, db.ErrConflict):
+		abortWithError(c, http.StatusConflict, CodeConflict, message, details)
+	case errors.Is(err, db.ErrForbidden):
+		abortWithError(c, http.StatusForbidden, CodeForbidden, message, details)
+	default:
+		internalError(c, fallback, err)
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/celerix-dev/celerix-flow/internal/fsck"
+	"github.com/gin-gonic/gin"
+)
+
+// CheckIntegrity reports inconsistencies between file records and blobs.
+// POST repairs them, GET only reports.
+func (h *Handler) CheckIntegrity(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	repair := c.Request.Method == http.MethodPost
+	report, err := fsck.Run(h.Store, h.StorageDir, repair)
+	if err != nil {
+		internalError(c, "Integrity check failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
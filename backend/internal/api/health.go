@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ActiveClientWindow is how recently a client must have loaded its persona,
+// which the app does on start, to count as active.
+const ActiveClientWindow = 24 * time.Hour
+
+// Healthz reports that the process is up and serving requests.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the store answers and the storage directory
+// accepts writes. Every check is listed so a failing probe says why.
+func (h *Handler) Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	if _, err := h.Store.GetPersonas(); err != nil {
+		checks["store"] = err.Error()
+		ready = false
+	} else {
+		checks["store"] = "ok"
+	}
+
+	if err := checkWritable(h.StorageDir); err != nil {
+		checks["storage"] = err.Error()
+		ready = false
+	} else {
+		checks["storage"] = "ok"
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
+
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// RegisterMetrics adds the gauges that are computed from the store at scrape
+// time. Call it once per registry.
+func (h *Handler) RegisterMetrics(reg *metrics.Registry) {
+	reg.NewGaugeFunc("flow_clients", "Registered clients.", func() float64 {
+		clients, _ := db.ListClients(h.Store)
+		return float64(len(clients))
+	})
+	reg.NewGaugeFunc("flow_active_clients", "Clients that loaded their persona in the last 24 hours.", func() float64 {
+		clients, _ := db.ListClients(h.Store)
+		since := time.Now().Add(-ActiveClientWindow).Unix()
+		active := 0
+		for _, cl := range clients {
+			if cl.LastActive >= since {
+				active++
+			}
+		}
+		return float64(active)
+	})
+}
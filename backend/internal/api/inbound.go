@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/events"
+	"github.com/celerix-dev/celerix-flow/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// EventKanbanChanged is sent on /events when cards were added to the
+// caller's board from outside, so open boards reload before they save.
+const EventKanbanChanged = "kanban.changed"
+
+// InboundHookInput creates an inbound hook. Column is the ID or title of
+// the column its cards go to when a request does not name one.
+type InboundHookInput struct {
+	Name   string `json:"name"`
+	Column string `json:"column"`
+}
+
+// InboundHook is an inbound hook as its owner sees it. The token is only
+// returned once, when the hook is created.
+type InboundHook struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Column     string `json:"column,omitempty"`
+	Path       string `json:"path"`
+	Token      string `json:"token,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at,omitempty"`
+}
+
+type InboundHookList struct {
+	Hooks []InboundHook `json:"hooks"`
+}
+
+func inboundHook(hook db.InboundHook) InboundHook {
+	return InboundHook{
+		ID:         hook.ID,
+		Name:       hook.Name,
+		Column:     hook.Column,
+		Path:       V1Prefix + "/hooks/" + hook.ID,
+		CreatedAt:  hook.CreatedAt,
+		LastUsedAt: hook.LastUsedAt,
+	}
+}
+
+func (h *Handler) ListInboundHooks(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	hooks, err := db.ListInboundHooks(h.Store, ownerID)
+	if err != nil {
+		internalError(c, "Failed to list inbound hooks", err)
+		return
+	}
+
+	list := InboundHookList{Hooks: make([]InboundHook, 0, len(hooks))}
+	for _, hook := range hooks {
+		list.Hooks = append(list.Hooks, inboundHook(hook))
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) CreateInboundHook(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	var input InboundHookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	token := webhook.NewSecret()
+	hook, err := db.CreateInboundHook(h.Store, ownerID, input.Name, input.Column, token, time.Now())
+	if err != nil {
+		respondError(c, err, "Failed to create inbound hook")
+		return
+	}
+
+	created := inboundHook(hook)
+	created.Token = token
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handler) DeleteInboundHook(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	hook, err := db.GetInboundHook(h.Store, c.Param("id"))
+	if err == nil && hook.Owner != ownerID {
+		err = db.NotFound("Inbound hook not found")
+	}
+	if err == nil {
+		err = db.DeleteInboundHook(h.Store, hook.ID)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to delete inbound hook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ReceiveInboundHook adds a card to the board of the hook's owner. It needs
+// no client ID; the hook's token, from X-Flow-Token or ?token, is the
+// credential.
+func (h *Handler) ReceiveInboundHook(c *gin.Context) {
+	token := c.GetHeader("X-Flow-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	now := time.Now()
+	hook, err := db.AuthenticateInboundHook(h.Store, c.Param("id"), token, now)
+	if err != nil {
+		respondError(c, err, "Failed to load inbound hook")
+		return
+	}
+
+	var input db.NewCard
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+	if input.Column == "" {
+		input.Column = hook.Column
+	}
+
+	change, err := db.AddCard(h.Store, hook.Owner, input, now)
+	if err != nil {
+		respondError(c, err, "Failed to add card")
+		return
+	}
+	logger(c).Info("card added by inbound hook", "hook", hook.ID, "owner_id", hook.Owner, "card", change.Card.ID)
+	h.emit(c, db.EventCardCreated, hook.Owner, change)
+	if h.Events != nil {
+		h.Events.Publish(hook.Owner, events.Event{Type: EventKanbanChanged, Data: change})
+	}
+
+	c.JSON(http.StatusCreated, change)
+}
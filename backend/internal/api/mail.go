@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/events"
+	"github.com/celerix-dev/celerix-flow/internal/mail"
+	"github.com/gin-gonic/gin"
+)
+
+type OutboxList struct {
+	Entries []db.OutboxEntry `json:"entries"`
+}
+
+// mailData is what the mail templates are executed with. Recipient is the
+// client's name; without one the templates use a neutral greeting.
+type mailData struct {
+	Recipient    string
+	Notification db.Notification
+	Overdue      bool
+}
+
+// mailTemplates picks the template of each notification type.
+var mailTemplates = map[string]string{
+	db.NotificationDueSoon:    mail.TemplateDueReminder,
+	db.NotificationOverdue:    mail.TemplateDueReminder,
+	db.NotificationAssigned:   mail.TemplateAssignment,
+	db.NotificationDownloaded: mail.TemplateShareLink,
+}
+
+func (h *Handler) GetEmailPrefs(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	prefs, err := db.GetEmailPrefs(h.Store, ownerID)
+	if err != nil {
+		respondError(c, err, "Failed to load email preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *Handler) UpdateEmailPrefs(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	var input db.EmailPrefs
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	prefs, err := db.SaveEmailPrefs(h.Store, ownerID, input)
+	if err != nil {
+		respondError(c, err, "Failed to save email preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// ListOutbox shows the emails waiting to be sent and those given up on.
+func (h *Handler) ListOutbox(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	entries, err := db.ListOutbox(h.Store)
+	if err != nil {
+		internalError(c, "Failed to list outbox", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, OutboxList{Entries: entries})
+}
+
+// notify records notifications for a client it has not had yet, pushes
+// them to its streams and queues the emails it opted into. It returns the
+// recorded notifications.
+func (h *Handler) notify(clientID string, notifications []db.Notification, now time.Time) ([]db.Notification, error) {
+	added, err := db.AddNotifications(h.Store, clientID, notifications, now)
+	if err != nil || len(added) == 0 {
+		return nil, err
+	}
+	for _, n := range added {
+		h.Events.Publish(clientID, events.Event{Type: EventNotification, Data: n})
+	}
+	if h.Mailer == nil {
+		return added, nil
+	}
+
+	prefs, err := db.GetEmailPrefs(h.Store, clientID)
+	if err != nil {
+		return added, err
+	}
+	// Never the client ID: it is a credential and mail is stored in the
+	// outbox admins can read
+	recipient := ""
+	if client, err := db.GetClient(h.Store, clientID); err == nil {
+		recipient = client.Name
+	}
+	for _, n := range added {
+		if !prefs.Wants(n.Type) {
+			continue
+		}
+		data := mailData{Recipient: recipient, Notification: n, Overdue: n.Type == db.NotificationOverdue}
+		msg, err := mail.Render(mailTemplates[n.Type], prefs.Email, data)
+		if err != nil {
+			return added, err
+		}
+		_, err = db.EnqueueMail(h.Store, db.OutboxEntry{
+			ClientID: clientID,
+			To:       msg.To,
+			Subject:  msg.Subject,
+			Text:     msg.Text,
+			HTML:     msg.HTML,
+		}, now)
+		if err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// DeliverMail tries to send every email in the outbox that is due. Failed
+// sends are retried later with a backoff. It returns how many were sent
+// and how many failed.
+func (h *Handler) DeliverMail(ctx context.Context, now time.Time) (sent, failed int, err error) {
+	if h.Mailer == nil {
+		return 0, 0, nil
+	}
+	due, err := db.DueMail(h.Store, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, e := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		msg := mail.Message{To: e.To, Subject: e.Subject, Text: e.Text, HTML: e.HTML}
+		if sendErr := h.Mailer.Send(ctx, msg); sendErr != nil {
+			failed++
+			retry, err := db.MailFailed(h.Store, e.ID, sendErr, now)
+			if err != nil {
+				return sent, failed, err
+			}
+			slog.Warn("sending email failed", "id", e.ID, "attempts", retry.Attempts, "status", retry.Status, "err", sendErr)
+			continue
+		}
+		sent++
+		if err := db.MailSent(h.Store, e.ID); err != nil {
+			return sent, failed, err
+		}
+	}
+	return sent, failed, nil
+}
+
+// RunOutbox delivers due emails every interval until ctx is cancelled.
+func (h *Handler) RunOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sent, failed, err := h.DeliverMail(ctx, time.Now())
+		if err != nil {
+			slog.Error("outbox delivery failed", "err", err)
+		} else if sent > 0 || failed > 0 {
+			slog.Info("delivered outbox", "sent", sent, "failed", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
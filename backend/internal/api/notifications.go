@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/events"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultDueSoon is how long before their due date cards are reported as
+// due soon when the handler does not set DueSoon.
+const DefaultDueSoon = 24 * time.Hour
+
+// Event types sent on /events.
+const (
+	EventNotification      = "notification"
+	EventNotificationsRead = "notifications.read"
+)
+
+// keepAlive is how often an idle event stream gets a comment, so proxies do
+// not close it.
+const keepAlive = 25 * time.Second
+
+type NotificationList struct {
+	Notifications []db.Notification `json:"notifications"`
+	Unread        int               `json:"unread"`
+}
+
+type MarkReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type MarkReadResponse struct {
+	Marked int `json:"marked"`
+}
+
+func (h *Handler) dueSoon() time.Duration {
+	if h.DueSoon <= 0 {
+		return DefaultDueSoon
+	}
+	return h.DueSoon
+}
+
+func (h *Handler) ListNotifications(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	list, unread, err := db.ListNotifications(h.Store, ownerID, c.Query("unread") == "true")
+	if err != nil {
+		respondError(c, err, "Failed to load notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationList{Notifications: list, Unread: unread})
+}
+
+// MarkNotificationsRead marks the listed notifications read, all of them if
+// the list is empty, and tells the caller's other streams.
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	var input MarkReadRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	marked, err := db.MarkNotificationsRead(h.Store, ownerID, input.IDs, time.Now())
+	if err != nil {
+		respondError(c, err, "Failed to mark notifications read")
+		return
+	}
+	if marked > 0 {
+		h.Events.Publish(ownerID, events.Event{Type: EventNotificationsRead, Data: input})
+	}
+
+	c.JSON(http.StatusOK, MarkReadResponse{Marked: marked})
+}
+
+// StreamEvents sends the caller's events as server-sent events until the
+// client goes away or the event bus is closed. Browsers cannot set headers
+// on an EventSource, so the client ID may also be given as ?client_id.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		ownerID = c.Query("client_id")
+	}
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+	if h.Events == nil {
+		internalError(c, "Event streams are not available", nil)
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger(c).Debug("cannot lift write deadline", "err", err)
+	}
+
+	stream, cancel := h.Events.Subscribe(ownerID)
+	defer cancel()
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteString(": connected\n\n")
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-stream:
+			if !ok {
+				return
+			}
+			c.SSEvent(e.Type, e.Data)
+		case <-ticker.C:
+			c.Writer.WriteString(": keep-alive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// CheckDueDates notifies clients of cards due soon or overdue that they
+// have not heard about yet. It returns how many notifications were
+// recorded. A client that cannot be notified is logged and skipped.
+func (h *Handler) CheckDueDates(now time.Time) (int, error) {
+	reminders, err := db.DueReminders(h.Store, now, h.dueSoon())
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for clientID, notifications := range reminders {
+		added, err := h.notify(clientID, notifications, now)
+		total += len(added)
+		if err == nil {
+			err = db.ForgetReminders(h.Store, clientID, notifications)
+		}
+		if err != nil {
+			slog.Error("due date reminders failed", "client_id", clientID, "err", err)
+		}
+	}
+	return total, nil
+}
+
+// RunDueDateReminders checks due dates every interval until ctx is
+// cancelled.
+func (h *Handler) RunDueDateReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		notified, err := h.CheckDueDates(time.Now())
+		if err != nil {
+			slog.Error("due date check failed", "err", err)
+		} else if notified > 0 {
+			slog.Info("sent due date reminders", "notifications", notified)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
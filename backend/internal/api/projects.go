@@ -0,0 +1,263 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+// Projects belong to the calling client and are stored under db.ProjectsKey,
+// so the frontend's /store/PROJECTS reads see the same data.
+//
+// Requests that remove projects or project users take ?cascade=block (the
+// default), clear or reassign with &target=<project or user ID> to decide
+// what happens to the kanban cards referencing them.
+
+// cascadeOptions reads the cascade query parameters; it answers the request
+// itself when they are invalid.
+func cascadeOptions(c *gin.Context) (db.CascadeOptions, bool) {
+	opts, err := db.ParseCascade(c.Query("cascade"), c.Query("target"))
+	if err != nil {
+		respondError(c, err, "Invalid cascade")
+		return opts, false
+	}
+	return opts, true
+}
+
+func (h *Handler) ListProjects(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	data, err := db.GetProjects(h.Store, ownerID)
+	if err != nil {
+		respondError(c, err, "Failed to load projects")
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetProject(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	project, err := db.GetProject(h.Store, ownerID, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to load project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *Handler) CreateProject(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	var input db.Project
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	project, err := db.CreateProject(h.Store, ownerID, input)
+	if err != nil {
+		respondError(c, err, "Failed to create project")
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *Handler) UpdateProject(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	opts, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+
+	var input db.Project
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	project, err := db.UpdateProject(h.Store, ownerID, c.Param("id"), input, opts)
+	if err != nil {
+		respondError(c, err, "Failed to update project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *Handler) DeleteProject(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	opts, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DeleteProject(h.Store, ownerID, c.Param("id"), opts); err != nil {
+		respondError(c, err, "Failed to delete project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) DeleteProjectUser(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+	opts, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DeleteProjectUser(h.Store, ownerID, c.Param("id"), c.Param("userId"), opts); err != nil {
+		respondError(c, err, "Failed to delete project user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetProjectBoard returns the cards of one project on its owner's board.
+// ?owner is the owner handle from /me/projects; other clients need to be
+// linked to a user of the project.
+func (h *Handler) GetProjectBoard(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	owner, err := db.ProjectOwner(h.Store, ownerID, c.Param("id"), c.Query("owner"))
+	var board db.KanbanBoard
+	if err == nil {
+		board, err = db.ProjectBoard(h.Store, owner, c.Param("id"), ownerID)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to load project board")
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// ListMemberships returns the projects, of any client, the caller is linked
+// to.
+func (h *Handler) ListMemberships(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	memberships, err := db.Memberships(h.Store, ownerID)
+	if err != nil {
+		respondError(c, err, "Failed to load memberships")
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// MyCards returns the cards of every board the caller can access that match
+// the query: assignee (a nickname, me or none), priority, due_before,
+// due_after, project and purpose. priority and purpose take comma-separated
+// lists.
+func (h *Handler) MyCards(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	filter := db.CardFilter{
+		Assignee:   c.Query("assignee"),
+		Priorities: splitList(c.Query("priority")),
+		DueBefore:  c.Query("due_before"),
+		DueAfter:   c.Query("due_after"),
+		ProjectID:  c.Query("project"),
+		Purposes:   splitList(c.Query("purpose")),
+	}
+	h.respondCards(c, ownerID, filter)
+}
+
+// MyWork returns the cards assigned to the caller across all boards, the
+// same as /me/cards?assignee=me.
+func (h *Handler) MyWork(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	h.respondCards(c, ownerID, db.CardFilter{Assignee: db.AssigneeMe})
+}
+
+func (h *Handler) respondCards(c *gin.Context, ownerID string, filter db.CardFilter) {
+	cards, err := db.FindCards(h.Store, ownerID, filter)
+	if err != nil {
+		respondError(c, err, "Failed to load cards")
+		return
+	}
+
+	c.JSON(http.StatusOK, cards)
+}
+
+// splitList splits a comma-separated query value, dropping empty items.
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// saveProjectsBlob validates a whole project list written through the
+// generic store endpoint, as the frontend does.
+func (h *Handler) saveProjectsBlob(c *gin.Context, ownerID string, input any) {
+	opts, ok := cascadeOptions(c)
+	if !ok {
+		return
+	}
+
+	data, err := db.DecodeProjects(input)
+	if err == nil {
+		err = db.SaveProjects(h.Store, ownerID, data, opts)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to save projects")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
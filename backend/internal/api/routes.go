@@ -0,0 +1,110 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec documents every route registered by RegisterRoutes. The tests
+// fail when the two drift apart; pkg/client is generated from it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec returns the OpenAPI 3 document of the API.
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// RegisterRoutes adds every API route to g; see Mount for the prefixes.
+func (h *Handler) RegisterRoutes(g gin.IRoutes) {
+	g.Use(h.ResolveCaller)
+
+	g.GET("/openapi.json", h.GetOpenAPI)
+	g.GET("/version", h.GetVersion)
+	g.GET("/persona", h.GetPersona)
+	g.POST("/persona/name", h.UpdateClientName)
+	g.POST("/persona/recover", h.RecoverPersona)
+	g.POST("/persona/admin", h.ActivateAdmin)
+	g.GET("/persona/export", h.ExportPersona)
+	g.POST("/persona/import", h.ImportPersona)
+	g.GET("/persona/email", h.GetEmailPrefs)
+	g.PUT("/persona/email", h.UpdateEmailPrefs)
+	g.GET("/persona/tokens", h.ListAPITokens)
+	g.POST("/persona/tokens", h.CreateAPIToken)
+	g.DELETE("/persona/tokens/:id", h.DeleteAPIToken)
+
+	// Kanban endpoints
+	g.GET("/kanban", h.GetKanban)
+	g.POST("/kanban", h.SaveKanban)
+
+	// Projects endpoints
+	g.GET("/projects", h.ListProjects)
+	g.POST("/projects", h.CreateProject)
+	g.GET("/projects/:id", h.GetProject)
+	g.PUT("/projects/:id", h.UpdateProject)
+	g.DELETE("/projects/:id", h.DeleteProject)
+	g.DELETE("/projects/:id/users/:userId", h.DeleteProjectUser)
+	g.GET("/projects/:id/board", h.GetProjectBoard)
+
+	// Work of the caller across every client's projects
+	g.GET("/me/projects", h.ListMemberships)
+	g.GET("/me/work", h.MyWork)
+	g.GET("/me/cards", h.MyCards)
+	g.GET("/search", h.Search)
+
+	// Notifications and the live event stream that pushes them
+	g.GET("/notifications", h.ListNotifications)
+	g.POST("/notifications/read", h.MarkNotificationsRead)
+	g.GET("/events", h.StreamEvents)
+
+	// Webhook subscriptions of the caller and their delivery logs
+	g.GET("/webhooks", h.ListWebhooks)
+	g.POST("/webhooks", h.CreateWebhook)
+	g.GET("/webhooks/:id", h.GetWebhook)
+	g.PUT("/webhooks/:id", h.UpdateWebhook)
+	g.DELETE("/webhooks/:id", h.DeleteWebhook)
+	g.GET("/webhooks/:id/deliveries", h.ListWebhookDeliveries)
+
+	// Inbound hooks add cards to the caller's board; /hooks/:id takes the
+	// hook's token instead of a client ID
+	g.GET("/inbound-hooks", h.ListInboundHooks)
+	g.POST("/inbound-hooks", h.CreateInboundHook)
+	g.DELETE("/inbound-hooks/:id", h.DeleteInboundHook)
+	g.POST("/hooks/:id", h.ReceiveInboundHook)
+
+	// Generic endpoints for key-value storage
+	g.GET("/store/:key", h.GetGeneric)
+	g.POST("/store/:key", h.SaveGeneric)
+
+	g.POST("/upload", h.UploadFile)
+	g.GET("/files", h.ListFiles)
+	g.GET("/files/:id", h.GetFileMetadata)
+	g.PUT("/files/:id", h.UpdateFile)
+	g.DELETE("/files/:id", h.DeleteFile)
+	g.GET("/clients", h.ListClients)
+	g.PUT("/clients/:id", h.UpdateClient)
+	g.DELETE("/clients/:id", h.DeleteClient)
+	g.GET("/download/:id", h.DownloadFile)
+
+	// Trash endpoints
+	g.GET("/trash", h.ListTrash)
+	g.DELETE("/trash", h.EmptyTrash)
+	g.POST("/trash/files/:id/restore", h.RestoreFile)
+	g.POST("/trash/clients/:id/restore", h.RestoreClient)
+
+	// Admin maintenance endpoints
+	g.GET("/admin/fsck", h.CheckIntegrity)
+	g.POST("/admin/fsck", h.CheckIntegrity)
+	g.GET("/admin/backup", h.ExportBackup)
+	g.POST("/admin/restore", h.RestoreBackup)
+	g.GET("/admin/outbox", h.ListOutbox)
+	g.GET("/admin/webhooks", h.ListAllWebhooks)
+	g.POST("/admin/webhooks", h.CreateInstanceWebhook)
+}
+
+func (h *Handler) GetOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/search"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchResponse lists search results, best first.
+type SearchResponse struct {
+	Results []search.Result `json:"results"`
+}
+
+// Search answers ?q= over the cards, projects and files the caller can see:
+// their own cards and projects, those of projects they are linked to, and
+// their own and public files (every file for admins). ?kind takes a
+// comma-separated list of card, project and file.
+func (h *Handler) Search(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+	if h.Index == nil {
+		internalError(c, "Search is not available", nil)
+		return
+	}
+
+	query := c.Query("q")
+	if strings.TrimSpace(query) == "" {
+		badRequest(c, "q is required")
+		return
+	}
+	kinds := splitList(c.Query("kind"))
+	for _, kind := range kinds {
+		if kind != search.KindCard && kind != search.KindProject && kind != search.KindFile {
+			badRequest(c, "kind must be card, project or file")
+			return
+		}
+	}
+	limit := defaultSearchLimit
+	if val := c.Query("limit"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 {
+			badRequest(c, "limit must be a positive number")
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	memberships, err := db.Memberships(h.Store, ownerID)
+	if err != nil {
+		respondError(c, err, "Failed to search")
+		return
+	}
+	shared := map[string]bool{}
+	for _, m := range memberships {
+		shared[m.OwnerID+"/"+m.Project.ID] = true
+	}
+	isAdmin := h.isAdmin(c)
+
+	results := h.Index.Search(search.Query{
+		Text:  query,
+		Kinds: kinds,
+		Limit: limit,
+		Visible: func(doc *search.Document) bool {
+			if doc.Kind == search.KindFile {
+				return isAdmin || doc.Public || doc.Owner == ownerID
+			}
+			return doc.Owner == ownerID || (doc.ProjectID != "" && shared[doc.Owner+"/"+doc.ProjectID])
+		},
+	})
+
+	c.JSON(http.StatusOK, SearchResponse{Results: results})
+}
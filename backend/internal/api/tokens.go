@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenKey holds the API token a request was authenticated with.
+const tokenKey = "flow.api_token"
+
+// fileRoutes are the routes a files-only token may call.
+var fileRoutes = []string{"/upload", "/files", "/files/:id", "/download/:id"}
+
+// tokenDenied prefixes the routes no API token may call: the persona routes
+// hand out the recovery code, which recovers the client ID, and manage the
+// tokens themselves; the admin routes expose every client.
+var tokenDenied = []string{"/persona", "/admin"}
+
+// APITokenInput creates an API token.
+type APITokenInput struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// APIToken is an API token as its owner sees it. The token itself is only
+// returned once, when it is created.
+type APIToken struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Scope      string `json:"scope"`
+	Token      string `json:"token,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at,omitempty"`
+}
+
+type APITokenList struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+func apiToken(t db.APIToken) APIToken {
+	return APIToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scope:      t.Scope,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+// apiRoute is the route pattern of a request without the API prefix.
+func apiRoute(c *gin.Context) string {
+	route := strings.TrimPrefix(c.FullPath(), V1Prefix)
+	if route == c.FullPath() {
+		route = strings.TrimPrefix(route, Prefix)
+	}
+	return route
+}
+
+// viaToken reports whether the request was authenticated with an API token.
+func viaToken(c *gin.Context) bool {
+	_, ok := c.Get(tokenKey)
+	return ok
+}
+
+// ResolveCaller resolves who a request acts for. Browsers and legacy
+// scripts name their client in X-Client-ID. Requests with an
+// "Authorization: Bearer" API token act for the token's owner within the
+// token's scope, never with admin rights; a forged X-Client-ID is replaced
+// by the owner.
+func (h *Handler) ResolveCaller(c *gin.Context) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		c.Next()
+		return
+	}
+
+	t, err := db.AuthenticateAPIToken(h.Store, strings.TrimSpace(token), time.Now())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.Header("WWW-Authenticate", `Bearer realm="flow"`)
+			unauthorized(c, "Invalid or revoked API token")
+			return
+		}
+		internalError(c, "Failed to check API token", err)
+		return
+	}
+
+	route := apiRoute(c)
+	switch {
+	case slices.ContainsFunc(tokenDenied, func(prefix string) bool { return strings.HasPrefix(route, prefix) }):
+		forbidden(c, "This endpoint is not available to API tokens")
+		return
+	case t.Scope == db.ScopeReadOnly && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead:
+		forbidden(c, "This API token is read-only")
+		return
+	case t.Scope == db.ScopeFiles && !slices.Contains(fileRoutes, route):
+		forbidden(c, "This API token is limited to files")
+		return
+	}
+
+	c.Request.Header.Set("X-Client-ID", t.Owner)
+	c.Set(tokenKey, t)
+	c.Next()
+}
+
+func (h *Handler) ListAPITokens(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	tokens, err := db.ListAPITokens(h.Store, ownerID)
+	if err != nil {
+		internalError(c, "Failed to list API tokens", err)
+		return
+	}
+
+	list := APITokenList{Tokens: make([]APIToken, 0, len(tokens))}
+	for _, t := range tokens {
+		list.Tokens = append(list.Tokens, apiToken(t))
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) CreateAPIToken(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	var input APITokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	t, token, err := db.CreateAPIToken(h.Store, ownerID, input.Name, input.Scope, webhook.NewSecret(), time.Now())
+	if err != nil {
+		respondError(c, err, "Failed to create API token")
+		return
+	}
+
+	created := apiToken(t)
+	created.Token = token
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *Handler) DeleteAPIToken(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	t, err := db.GetAPIToken(h.Store, c.Param("id"))
+	if err == nil && t.Owner != ownerID {
+		err = db.NotFound("API token not found")
+	}
+	if err == nil {
+		err = db.DeleteAPIToken(h.Store, t.ID)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to revoke API token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTrashRetention is used when the handler has no retention configured.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+type TrashResponse struct {
+	Files          []db.FileRecord   `json:"files"`
+	Clients        []db.ClientRecord `json:"clients,omitempty"`
+	RetentionHours int64             `json:"retention_hours"`
+}
+
+func (h *Handler) trashRetention() time.Duration {
+	if h.TrashRetention <= 0 {
+		return DefaultTrashRetention
+	}
+	return h.TrashRetention
+}
+
+func (h *Handler) ListTrash(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	isAdmin := h.isAdmin(c)
+	if !isAdmin && ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	filter := ownerID
+	if isAdmin {
+		filter = ""
+	}
+
+	files, err := db.ListTrashedFiles(h.Store, filter)
+	if err != nil {
+		internalError(c, "Failed to list trash", err)
+		return
+	}
+
+	resp := TrashResponse{
+		Files:          files,
+		RetentionHours: int64(h.trashRetention() / time.Hour),
+	}
+	if resp.Files == nil {
+		resp.Files = []db.FileRecord{}
+	}
+
+	if isAdmin {
+		clients, err := db.ListTrashedClients(h.Store)
+		if err != nil {
+			internalError(c, "Failed to list trash", err)
+			return
+		}
+		resp.Clients = clients
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) RestoreFile(c *gin.Context) {
+	id := c.Param("id")
+	record, err := db.GetTrashedFileRecord(h.Store, id)
+	if err != nil {
+		respondError(c, err, "Failed to load file")
+		return
+	}
+
+	// Permission check: admin or owner
+	ownerID := c.GetHeader("X-Client-ID")
+	if !h.isAdmin(c) && record.OwnerID != ownerID {
+		forbidden(c, "You don't have permission to restore this file")
+		return
+	}
+
+	if err := db.RestoreFileRecord(h.Store, id); err != nil {
+		internalError(c, "Failed to restore file", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) RestoreClient(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	id := c.Param("id")
+	if err := db.RestoreClient(h.Store, id); err != nil {
+		respondError(c, err, "Failed to restore client")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) EmptyTrash(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	purged, err := h.PurgeTrash(time.Now().Add(time.Second))
+	if err != nil {
+		internalError(c, "Failed to empty trash", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "purged": purged})
+}
+
+// PurgeTrash permanently removes every trashed file and client deleted before
+// the cutoff, including the file blobs. It returns the number of purged items.
+func (h *Handler) PurgeTrash(cutoff time.Time) (int, error) {
+	files, err := db.ListTrashedFiles(h.Store, "")
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, f := range files {
+		if f.DeletedAt >= cutoff.Unix() {
+			continue
+		}
+		if !storage.Within(h.StorageDir, f.StoredPath) {
+			// Never follow a record out of the storage directory
+			slog.Warn("not deleting blob outside the storage directory", "file", f.ID, "path", f.StoredPath)
+		} else if err := storage.DeleteFile(f.StoredPath); err != nil && !os.IsNotExist(err) {
+			// Keep the record so the purge is retried on the next run
+			slog.Error("failed to delete blob", "path", f.StoredPath, "err", err)
+			continue
+		}
+		if err := db.PurgeFileRecord(h.Store, f.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	clients, err := db.ListTrashedClients(h.Store)
+	if err != nil {
+		return purged, err
+	}
+	for _, cl := range clients {
+		if cl.DeletedAt >= cutoff.Unix() {
+			continue
+		}
+		if err := h.purgeClient(cl); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// purgeClient permanently removes a trashed client. For cascading deletes all
+// of their store keys and blobs go with it: blobs are staged first so that a
+// failing store write can roll the whole purge back.
+func (h *Handler) purgeClient(client db.ClientRecord) error {
+	if !client.CascadeDelete {
+		return db.PurgeClient(h.Store, client.ID)
+	}
+
+	files, err := db.ClientFiles(h.Store, client.ID)
+	if err != nil {
+		return err
+	}
+
+	tx := db.NewTx(h.Store)
+	var staged []string
+	err = func() error {
+		for _, f := range files {
+			if !storage.Within(h.StorageDir, f.StoredPath) {
+				slog.Warn("not deleting blob outside the storage directory", "file", f.ID, "path", f.StoredPath)
+				continue
+			}
+			path, err := storage.StageDelete(f.StoredPath)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			tx.OnRollback(func() error { return storage.UnstageDelete(path) })
+			staged = append(staged, path)
+		}
+		return db.PurgeClientData(tx, client.ID)
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			slog.Error("rollback of client purge failed", "client_id", client.ID, "err", rbErr)
+		}
+		return err
+	}
+
+	for _, path := range staged {
+		if err := storage.DeleteFile(path); err != nil {
+			slog.Error("failed to delete blob", "path", path, "err", err)
+		}
+	}
+	return nil
+}
+
+// RunTrashPurger purges expired trash every interval until ctx is cancelled.
+func (h *Handler) RunTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := h.PurgeTrash(time.Now().Add(-h.trashRetention()))
+		if err != nil {
+			slog.Error("trash purge failed", "err", err)
+		} else if purged > 0 {
+			slog.Info("purged trash", "items", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookInput creates or updates a webhook. An empty secret is generated
+// on create and left unchanged on update.
+type WebhookInput struct {
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret"`
+	Events   []string `json:"events"`
+	Disabled bool     `json:"disabled"`
+}
+
+type WebhookList struct {
+	Webhooks []db.Webhook `json:"webhooks"`
+}
+
+type DeliveryList struct {
+	Deliveries []db.WebhookDelivery `json:"deliveries"`
+}
+
+// ClientDeletedEvent is the data of a client.deleted event. ID and Target
+// are owner handles, like the event's owner.
+type ClientDeletedEvent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Mode is cascade or transfer, as given to DELETE /clients/{id}.
+	Mode   string `json:"mode"`
+	Target string `json:"target,omitempty"`
+}
+
+func (in WebhookInput) webhook() db.Webhook {
+	return db.Webhook{URL: in.URL, Secret: in.Secret, Events: in.Events, Disabled: in.Disabled}
+}
+
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+
+	hooks, err := db.ListWebhooks(h.Store, ownerID, false)
+	if err != nil {
+		internalError(c, "Failed to list webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookList{Webhooks: hooks})
+}
+
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return
+	}
+	h.createWebhook(c, ownerID)
+}
+
+// createWebhook creates a webhook of owner, an instance-wide one if owner
+// is empty.
+func (h *Handler) createWebhook(c *gin.Context, owner string) {
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	w := input.webhook()
+	w.Owner = owner
+	created, err := db.CreateWebhook(h.Store, w, webhook.NewSecret, time.Now())
+	if err != nil {
+		respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// ownWebhook loads the webhook of the path for its owner or an admin; to
+// anyone else it does not exist.
+func (h *Handler) ownWebhook(c *gin.Context) (*db.Webhook, bool) {
+	ownerID := c.GetHeader("X-Client-ID")
+	if ownerID == "" {
+		badRequest(c, "X-Client-ID header is required")
+		return nil, false
+	}
+
+	w, err := db.GetWebhook(h.Store, c.Param("id"))
+	if err == nil && w.Owner != ownerID && !h.isAdmin(c) {
+		err = db.NotFound("Webhook not found")
+	}
+	if err != nil {
+		respondError(c, err, "Failed to load webhook")
+		return nil, false
+	}
+	return w, true
+}
+
+func (h *Handler) GetWebhook(c *gin.Context) {
+	w, ok := h.ownWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, w)
+}
+
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	old, ok := h.ownWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		badRequest(c, err.Error())
+		return
+	}
+
+	w := input.webhook()
+	w.ID = old.ID
+	updated, err := db.UpdateWebhook(h.Store, w)
+	if err != nil {
+		respondError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	w, ok := h.ownWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DeleteWebhook(h.Store, w.ID); err != nil {
+		respondError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	w, ok := h.ownWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := db.ListDeliveries(h.Store, w.ID)
+	if err != nil {
+		internalError(c, "Failed to list deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DeliveryList{Deliveries: deliveries})
+}
+
+// ListAllWebhooks lists the webhooks of every client and the instance-wide
+// ones.
+func (h *Handler) ListAllWebhooks(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+
+	hooks, err := db.ListWebhooks(h.Store, "", true)
+	if err != nil {
+		internalError(c, "Failed to list webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookList{Webhooks: hooks})
+}
+
+// CreateInstanceWebhook creates a webhook that receives the events of every
+// client.
+func (h *Handler) CreateInstanceWebhook(c *gin.Context) {
+	if !h.isAdmin(c) {
+		forbidden(c, "Admin access required")
+		return
+	}
+	h.createWebhook(c, "")
+}
+
+// emit queues an event about the data of owner for the webhooks that
+// subscribe to it. Failures are logged; they never fail the request that
+// caused the event.
+// QueueClientDeleted queues the client.deleted event of client, deleted in
+// mode and, for transfers, handed to targetID. Both DELETE /clients/{id}
+// and the client delete command call it.
+func QueueClientDeleted(s db.CelerixStore, client *db.ClientRecord, mode, targetID string, now time.Time) error {
+	deleted := ClientDeletedEvent{ID: db.OwnerHandle(client.ID), Name: client.Name, Mode: mode, Target: db.OwnerHandle(targetID)}
+	_, err := db.QueueWebhookEvent(s, db.WebhookEvent{Type: db.EventClientDeleted, OwnerID: client.ID, Data: deleted}, now)
+	return err
+}
+
+func (h *Handler) emit(c *gin.Context, event, owner string, data any) {
+	e := db.WebhookEvent{Type: event, OwnerID: owner, Data: data}
+	if _, err := db.QueueWebhookEvent(h.Store, e, time.Now()); err != nil {
+		logger(c).Error("failed to queue webhook event", "event", event, "err", err)
+	}
+}
+
+// kanbanChanged notifies the clients cards were assigned to and queues the
+// card events of a saved board.
+func (h *Handler) kanbanChanged(c *gin.Context, ownerID string, changes db.KanbanChanges) {
+	for clientID, notifications := range changes.Assigned {
+		if _, err := h.notify(clientID, notifications, time.Now()); err != nil {
+			logger(c).Error("failed to notify assignees", "client_id", clientID, "err", err)
+		}
+	}
+	for _, e := range []struct {
+		event   string
+		changes []db.CardChange
+	}{
+		{db.EventCardCreated, changes.Created},
+		{db.EventCardMoved, changes.Moved},
+		{db.EventCardDeleted, changes.Deleted},
+	} {
+		for _, change := range e.changes {
+			h.emit(c, e.event, ownerID, change)
+		}
+	}
+}
+
+// DeliverWebhooks posts every webhook delivery that is due. Failed ones are
+// retried later with a backoff. It returns how many were delivered and how
+// many failed.
+func (h *Handler) DeliverWebhooks(ctx context.Context, now time.Time) (delivered, failed int, err error) {
+	if h.Webhooks == nil {
+		return 0, 0, nil
+	}
+	due, err := db.DueDeliveries(h.Store, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		w, err := db.GetWebhook(h.Store, d.WebhookID)
+		if err != nil {
+			// Deleted while the delivery was queued
+			continue
+		}
+		status, sendErr := h.Webhooks.Send(ctx, webhook.Request{
+			URL:        w.URL,
+			Secret:     w.Secret,
+			Event:      d.Event,
+			DeliveryID: d.ID,
+			Body:       d.Payload,
+			// Only admins can point instance-wide webhooks at the
+			// instance's own network
+			AllowPrivate: w.Owner == "",
+		})
+		attempt, err := db.DeliveryAttempted(h.Store, d.ID, status, sendErr, now)
+		if err != nil {
+			return delivered, failed, err
+		}
+		if sendErr != nil {
+			failed++
+			slog.Warn("webhook delivery failed", "webhook", w.ID, "delivery", d.ID, "attempts", attempt.Attempts, "status", attempt.Status, "err", sendErr)
+			continue
+		}
+		delivered++
+	}
+	return delivered, failed, nil
+}
+
+// RunWebhooks delivers due webhook events every interval until ctx is
+// cancelled.
+func (h *Handler) RunWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, failed, err := h.DeliverWebhooks(ctx, time.Now())
+		if err != nil {
+			slog.Error("webhook delivery failed", "err", err)
+		} else if delivered > 0 || failed > 0 {
+			slog.Info("delivered webhooks", "delivered", delivered, "failed", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,425 @@
+// Package backup writes and restores full instance snapshots: every flow key
+// of the store plus the uploaded blobs, packed as a tar.gz with a manifest.
+//
+// Archive layout:
+//
+//	manifest.json          Manifest, always the first entry
+//	store/<persona>.json   flow keys of one persona
+//	blobs/<name>           uploaded files, named after their stored file name
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/storage"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// SchemaVersion is bumped whenever the archive layout or the meaning of the
+// stored keys changes in a way older releases cannot read.
+const SchemaVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	storeDir     = "store/"
+	blobDir      = "blobs/"
+)
+
+const (
+	// KindInstance archives hold every persona of an instance.
+	KindInstance = "instance"
+	// KindPersona archives hold the data of a single client, see ExportPersona.
+	KindPersona = "persona"
+)
+
+type Manifest struct {
+	SchemaVersion int         `json:"schema_version"`
+	Kind          string      `json:"kind"`
+	AppVersion    string      `json:"app_version"`
+	CreatedAt     int64       `json:"created_at"`
+	Personas      []string    `json:"personas"`
+	Keys          int         `json:"keys"`
+	Blobs         []BlobEntry `json:"blobs"`
+}
+
+type BlobEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Export writes a snapshot of the store and the blobs its file records point
+// to. The store is read in a single DumpApp call, so the key set is
+// consistent; blobs uploaded after that call are not part of the snapshot.
+func Export(w io.Writer, s db.CelerixStore, appVersion string) (*Manifest, error) {
+	data, err := s.DumpApp(db.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		SchemaVersion: SchemaVersion,
+		Kind:          KindInstance,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now().Unix(),
+	}
+	return manifest, writeArchive(w, s, manifest, data)
+}
+
+// writeArchive completes the manifest with the personas, key count and blobs
+// of data and writes the archive.
+func writeArchive(w io.Writer, s db.CelerixStore, manifest *Manifest, data map[string]map[string]any) error {
+	blobPaths := make(map[string]string)
+	for persona, keys := range data {
+		manifest.Personas = append(manifest.Personas, persona)
+		manifest.Keys += len(keys)
+		for k := range keys {
+			if !isFileKey(k) {
+				continue
+			}
+			r, err := sdk.Get[db.FileRecord](s, persona, db.AppID, k)
+			if err != nil {
+				return fmt.Errorf("reading %s/%s: %w", persona, k, err)
+			}
+			info, err := os.Stat(r.StoredPath)
+			if err != nil {
+				// fsck reports the dangling record; the snapshot goes on without it
+				continue
+			}
+			name := filepath.Base(r.StoredPath)
+			blobPaths[name] = r.StoredPath
+			manifest.Blobs = append(manifest.Blobs, BlobEntry{Name: name, Size: info.Size()})
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeJSON(tw, manifestName, manifest); err != nil {
+		return err
+	}
+	for persona, keys := range data {
+		if err := writeJSON(tw, storeDir+persona+".json", keys); err != nil {
+			return err
+		}
+	}
+	for _, b := range manifest.Blobs {
+		if err := writeBlob(tw, blobDir+b.Name, blobPaths[b.Name], b.Size); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore replaces all flow data and blobs with the contents of an archive
+// created by Export. The manifest is validated before anything is touched,
+// blobs are unpacked into a staging directory and only moved into place once
+// the whole archive has been read and checked.
+func Restore(r io.Reader, s db.CelerixStore, storageDir, appVersion string) (*Manifest, error) {
+	staging, err := os.MkdirTemp(storageDir, ".restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, personas, err := readArchive(r, KindInstance, appVersion, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	// The store swap and the blob moves succeed or are rolled back together,
+	// so the store never points at blobs left behind in staging
+	tx := db.NewTx(s)
+	restored := make(map[string]bool)
+	var replaced []string
+	oldBlobs, err := replaceStore(tx, personas, storageDir)
+	if err == nil {
+		replaced, err = moveBlobs(tx, manifest.Blobs, staging, storageDir, restored)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return nil, err
+	}
+
+	// Blobs of the replaced data are no longer referenced
+	for _, p := range replaced {
+		storage.DeleteFile(p)
+	}
+	for _, p := range oldBlobs {
+		if !restored[absPath(p)] {
+			os.Remove(p)
+		}
+	}
+
+	return manifest, nil
+}
+
+// readArchive validates the manifest of an archive of the given kind, decodes
+// the store entries and unpacks the blobs into staging, checking them against
+// the manifest.
+func readArchive(r io.Reader, kind, appVersion, staging string) (*Manifest, map[string]map[string]json.RawMessage, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, db.Validation("not a gzip archive").Wrap(err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, nil, db.Validation("archive does not start with %s", manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, nil, db.Validation("invalid manifest").Wrap(err)
+	}
+	if err := validate(&manifest, kind, appVersion); err != nil {
+		return nil, nil, err
+	}
+	sizes := make(map[string]int64, len(manifest.Blobs))
+	for _, b := range manifest.Blobs {
+		if !validBlobName(b.Name) {
+			return nil, nil, db.Validation("invalid blob name %q in manifest", b.Name)
+		}
+		sizes[b.Name] = b.Size
+	}
+
+	personas := make(map[string]map[string]json.RawMessage)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, db.Validation("reading archive").Wrap(err)
+		}
+
+		switch {
+		case strings.HasPrefix(hdr.Name, storeDir):
+			persona := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, storeDir), ".json")
+			if persona == "" || strings.ContainsAny(persona, `/\`) || strings.Contains(persona, "..") {
+				return nil, nil, db.Validation("invalid persona name in %q", hdr.Name)
+			}
+			var keys map[string]json.RawMessage
+			if err := json.NewDecoder(tr).Decode(&keys); err != nil {
+				return nil, nil, db.Validation("invalid store entry %s", hdr.Name).Wrap(err)
+			}
+			personas[persona] = keys
+
+		case strings.HasPrefix(hdr.Name, blobDir):
+			name := strings.TrimPrefix(hdr.Name, blobDir)
+			if !validBlobName(name) {
+				return nil, nil, db.Validation("invalid blob name %q", hdr.Name)
+			}
+			size, ok := sizes[name]
+			if !ok {
+				return nil, nil, db.Validation("blob %s is not listed in the manifest", name)
+			}
+			if err := extractBlob(tr, filepath.Join(staging, name), size); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if len(personas) != len(manifest.Personas) {
+		return nil, nil, db.Validation("archive holds %d personas, manifest lists %d", len(personas), len(manifest.Personas))
+	}
+	for _, b := range manifest.Blobs {
+		info, err := os.Stat(filepath.Join(staging, b.Name))
+		if err != nil {
+			return nil, nil, db.Validation("blob %s is missing from the archive", b.Name)
+		}
+		if info.Size() != b.Size {
+			return nil, nil, db.Validation("blob %s has %d bytes, manifest says %d", b.Name, info.Size(), b.Size)
+		}
+	}
+
+	return &manifest, personas, nil
+}
+
+// moveBlobs moves the restored blobs from staging into storageDir, marking
+// them in restored. Blobs they replace are staged for deletion and returned;
+// the caller deletes them once tx is not rolled back anymore.
+func moveBlobs(tx *db.Tx, blobs []BlobEntry, staging, storageDir string, restored map[string]bool) ([]string, error) {
+	var replaced []string
+	for _, b := range blobs {
+		src, dst := filepath.Join(staging, b.Name), filepath.Join(storageDir, b.Name)
+		old, err := storage.StageDelete(dst)
+		if err == nil {
+			tx.OnRollback(func() error { return storage.UnstageDelete(old) })
+			replaced = append(replaced, old)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return nil, fmt.Errorf("moving blob %s into place: %w", b.Name, err)
+		}
+		tx.OnRollback(func() error { return os.Rename(dst, src) })
+		restored[absPath(dst)] = true
+	}
+	return replaced, nil
+}
+
+// replaceStore swaps every flow key for the restored ones within tx. File
+// records are rewritten to point into storageDir. It returns the blob paths
+// of the replaced file records. The caller rolls tx back on error.
+func replaceStore(tx *db.Tx, personas map[string]map[string]json.RawMessage, storageDir string) ([]string, error) {
+	s := tx.Store()
+	existing, err := s.DumpApp(db.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldBlobs []string
+	for persona, keys := range existing {
+		for k := range keys {
+			if isFileKey(k) {
+				if r, err := sdk.Get[db.FileRecord](s, persona, db.AppID, k); err == nil {
+					oldBlobs = append(oldBlobs, r.StoredPath)
+				}
+			}
+			if err := tx.Delete(persona, k); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for persona, keys := range personas {
+		for k, raw := range keys {
+			var val any
+			if isFileKey(k) {
+				var r db.FileRecord
+				if err := json.Unmarshal(raw, &r); err != nil {
+					return nil, db.Validation("invalid file record %s/%s", persona, k).Wrap(err)
+				}
+				r.StoredPath = filepath.Join(storageDir, filepath.Base(r.StoredPath))
+				val = r
+			} else if err := json.Unmarshal(raw, &val); err != nil {
+				return nil, db.Validation("invalid value %s/%s", persona, k).Wrap(err)
+			}
+			if err := tx.Set(persona, k, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return oldBlobs, nil
+}
+
+func validate(m *Manifest, kind, appVersion string) error {
+	// Archives written before kinds existed are instance backups
+	if m.Kind == "" {
+		m.Kind = KindInstance
+	}
+	if m.Kind != kind {
+		return db.Validation("archive is a %s export, expected a %s export", m.Kind, kind)
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > SchemaVersion {
+		return db.Validation("unsupported backup schema version %d (this release reads up to %d)", m.SchemaVersion, SchemaVersion)
+	}
+	if compareVersions(m.AppVersion, appVersion) > 0 {
+		return db.Validation("backup was made by flow %s, which is newer than this release (%s)", m.AppVersion, appVersion)
+	}
+	return nil
+}
+
+// compareVersions compares dotted numeric versions ("1.2.3"). Missing or
+// non-numeric parts count as zero, so unknown versions never block a restore.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// validBlobName reports whether name can be used as a file name inside the
+// staging and storage directories.
+func validBlobName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func isFileKey(k string) bool {
+	return strings.HasPrefix(k, db.FileKeyPrefix) || strings.HasPrefix(k, db.TrashFileKeyPrefix)
+}
+
+func writeJSON(tw *tar.Writer, name string, v any) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(raw)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(raw)
+	return err
+}
+
+func writeBlob(tw *tar.Writer, name, src string, size int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	// Copy exactly the announced size, even if the blob grew meanwhile
+	_, err = io.CopyN(tw, f, size)
+	return err
+}
+
+// extractBlob copies a blob of at most size bytes to dst; a larger blob is
+// rejected before more than one extra byte is written.
+func extractBlob(r io.Reader, dst string, size int64) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, io.LimitReader(r, size+1))
+	if err != nil {
+		return err
+	}
+	if n > size {
+		return db.Validation("blob %s is larger than the %d bytes listed in the manifest", filepath.Base(dst), size)
+	}
+	return nil
+}
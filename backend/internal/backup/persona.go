@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/google/uuid"
+)
+
+// ExportPersona writes a takeout archive with everything a single client
+// owns: kanban, projects, templates, widgets, logs, other generic keys and
+// their live files. Trashed files are left behind.
+func ExportPersona(w io.Writer, s db.CelerixStore, clientID, appVersion string) (*Manifest, error) {
+	keys, err := db.GetPersonaKeys(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]any)
+	for k, v := range keys {
+		if !isTrashKey(k) {
+			data[k] = v
+		}
+	}
+
+	manifest := &Manifest{
+		SchemaVersion: SchemaVersion,
+		Kind:          KindPersona,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now().Unix(),
+	}
+	return manifest, writeArchive(w, s, manifest, map[string]map[string]any{clientID: data})
+}
+
+// ImportPersona loads a takeout archive into the persona of clientID.
+//
+// With replace set, the client's current keys are removed first and their
+// files are moved to the trash; its email preferences and notifications are
+// kept and never taken from the archive. Otherwise the archive is merged: keys from
+// the archive overwrite keys of the same name, files are added. Imported
+// files always get a fresh ID and download link so they cannot collide with
+// files already on this instance.
+func ImportPersona(r io.Reader, s db.CelerixStore, storageDir, clientID, appVersion string, replace bool) (*Manifest, error) {
+	staging, err := os.MkdirTemp(storageDir, ".import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, personas, err := readArchive(r, KindPersona, appVersion, staging)
+	if err != nil {
+		return nil, err
+	}
+	if len(personas) != 1 {
+		return nil, db.Validation("a persona export holds exactly one persona, found %d", len(personas))
+	}
+	var keys map[string]json.RawMessage
+	for _, k := range personas {
+		keys = k
+	}
+	// readArchive checked that every blob of the manifest was extracted
+	extracted := make(map[string]bool)
+	for _, b := range manifest.Blobs {
+		extracted[b.Name] = true
+	}
+
+	// The board and projects are held to the same rules as when they are
+	// saved through the API
+	var projects *db.ProjectsData
+	var board *db.KanbanBoard
+	if raw, ok := keys[db.ProjectsKey]; ok {
+		var val any
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return nil, db.Validation("invalid value %s", db.ProjectsKey).Wrap(err)
+		}
+		data, err := db.DecodeProjects(val)
+		if err != nil {
+			return nil, err
+		}
+		projects = &data
+	}
+	if raw, ok := keys[db.KanbanKey]; ok {
+		var val any
+		if err := json.Unmarshal(raw, &val); err != nil {
+			return nil, db.Validation("invalid value %s", db.KanbanKey).Wrap(err)
+		}
+		b, err := db.DecodeKanban(val)
+		if err != nil {
+			return nil, err
+		}
+		board = &b
+	}
+	if err := db.CheckImportedBoard(s, clientID, projects, board, replace); err != nil {
+		return nil, err
+	}
+
+	existing, err := db.GetPersonaKeys(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	tx := db.NewTx(s)
+	err = func() error {
+		if replace {
+			for k := range existing {
+				switch {
+				case isTrashKey(k) || db.PersonalKey(k):
+					continue
+				case strings.HasPrefix(k, db.FileKeyPrefix):
+					var rec db.FileRecord
+					if err := remarshal(existing[k], &rec); err != nil {
+						return err
+					}
+					rec.DeletedAt = now
+					rec.OwnerName = ""
+					if err := tx.Set(clientID, db.TrashFileKeyPrefix+rec.ID, rec); err != nil {
+						return err
+					}
+				}
+				if err := tx.Delete(clientID, k); err != nil {
+					return err
+				}
+			}
+		}
+
+		for k, raw := range keys {
+			if isTrashKey(k) || db.PersonalKey(k) {
+				continue
+			}
+
+			if !strings.HasPrefix(k, db.FileKeyPrefix) {
+				var val any
+				switch k {
+				case db.ProjectsKey:
+					val = *projects
+				case db.KanbanKey:
+					val = *board
+				default:
+					if err := json.Unmarshal(raw, &val); err != nil {
+						return db.Validation("invalid value %s", k).Wrap(err)
+					}
+				}
+				if err := tx.Set(clientID, k, val); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var rec db.FileRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return db.Validation("invalid file record %s", k).Wrap(err)
+			}
+			name := filepath.Base(rec.StoredPath)
+			if !validBlobName(name) {
+				return db.Validation("invalid stored path in file record %s", k)
+			}
+			if !extracted[name] {
+				// The blob was already missing on the exporting instance
+				continue
+			}
+			blob := filepath.Join(staging, name)
+			info, err := os.Lstat(blob)
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return db.Validation("blob %s is not a regular file", name)
+			}
+
+			rec.ID = uuid.New().String()
+			rec.StoredPath = filepath.Join(storageDir, rec.ID)
+			rec.DownloadLink = uuid.New().String()
+			rec.OwnerID = clientID
+			rec.OwnerName = ""
+			rec.DeletedAt = 0
+			rec.TrashedWith = ""
+
+			if err := os.Rename(blob, rec.StoredPath); err != nil {
+				return err
+			}
+			dst := rec.StoredPath
+			tx.OnRollback(func() error { return os.Remove(dst) })
+
+			if err := tx.Set(clientID, db.FileKeyPrefix+rec.ID, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func isTrashKey(k string) bool {
+	return strings.HasPrefix(k, db.TrashFileKeyPrefix) || strings.HasPrefix(k, db.TrashClientKeyPrefix)
+}
+
+func remarshal(in any, out any) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
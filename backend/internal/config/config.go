@@ -0,0 +1,396 @@
+// Package config loads the flow configuration from an optional YAML file,
+// environment variables and command line flags, in increasing order of
+// precedence, and validates it before anything is started.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/cors"
+	"github.com/celerix-dev/celerix-flow/internal/logging"
+	"github.com/celerix-dev/celerix-flow/internal/mail"
+	"github.com/goccy/go-yaml"
+	"github.com/google/uuid"
+)
+
+// EnvFile names the environment variable pointing at the config file when no
+// -config flag is given.
+const EnvFile = "FLOW_CONFIG"
+
+type Config struct {
+	// Env is "dev" for local development (debug mode, frontend proxy) or
+	// "production".
+	Env  string `yaml:"env"`
+	Port int    `yaml:"port"`
+
+	DataDir    string `yaml:"data_dir"`
+	StorageDir string `yaml:"storage_dir"`
+
+	// Namespace seeds the deterministic client IDs derived from recovery
+	// codes. It must never change for an existing instance.
+	Namespace   string `yaml:"namespace"`
+	AdminSecret string `yaml:"admin_secret"`
+
+	TrashRetention time.Duration `yaml:"trash_retention"`
+
+	// DueSoon is how long before their due date cards are reported as due
+	// soon; ReminderInterval is how often due dates are checked.
+	DueSoon          time.Duration `yaml:"due_soon"`
+	ReminderInterval time.Duration `yaml:"reminder_interval"`
+
+	// SMTPHost enables notification emails, which clients opt into. SMTPTLS
+	// is starttls, tls (implicit, usually port 465) or none. Queued emails
+	// are sent every OutboxInterval.
+	SMTPHost       string        `yaml:"smtp_host"`
+	SMTPPort       int           `yaml:"smtp_port"`
+	SMTPUsername   string        `yaml:"smtp_username"`
+	SMTPPassword   string        `yaml:"smtp_password"`
+	SMTPFrom       string        `yaml:"smtp_from"`
+	SMTPTLS        string        `yaml:"smtp_tls"`
+	OutboxInterval time.Duration `yaml:"outbox_interval"`
+
+	// WebhookInterval is how often queued webhook deliveries are sent.
+	WebhookInterval time.Duration `yaml:"webhook_interval"`
+
+	// HTTP server timeouts. Read and write timeouts bound whole requests, so
+	// they must leave room for large uploads and backups; zero disables them.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// LogLevel is debug, info, warn or error; LogFormat is text or json.
+	LogLevel  string     `yaml:"log_level"`
+	LogFormat string     `yaml:"log_format"`
+	Level     slog.Level `yaml:"-"`
+
+	// TLSCertFile and TLSKeyFile enable HTTPS on Port. The files are watched
+	// and a renewed certificate is used without a restart.
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// RedirectPort, when set with TLS, runs a plain HTTP listener that
+	// redirects every request to HTTPS.
+	RedirectPort int `yaml:"redirect_port"`
+	// HSTSMaxAge is sent in Strict-Transport-Security when TLS is on; zero
+	// disables the header.
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains"`
+
+	// ShutdownTimeout is how long in-flight requests may take to finish
+	// after a shutdown signal before connections are closed.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DevServerURL is where the frontend dev server runs when Env is "dev".
+	DevServerURL string `yaml:"dev_server_url"`
+
+	// CORSOrigins may call the API cross-origin with credentials. They are
+	// used for the default policies when CORS is empty.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// CORS replaces the default policies entirely.
+	CORS []cors.Policy `yaml:"cors"`
+
+	// APIAliasSunset is the date (YYYY-MM-DD) after which the unversioned
+	// /api alias of /api/v1 answers 410 Gone. Empty keeps it working.
+	APIAliasSunset string `yaml:"api_alias_sunset"`
+	// AliasSunset is the parsed APIAliasSunset, set by Validate.
+	AliasSunset time.Time `yaml:"-"`
+
+	// CelerixNamespace is the parsed Namespace, set by Validate.
+	CelerixNamespace uuid.UUID `yaml:"-"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Env:            "production",
+		Port:           8080,
+		DataDir:        "./data",
+		TrashRetention: 30 * 24 * time.Hour,
+		DevServerURL:   "http://localhost:5173",
+
+		DueSoon:          24 * time.Hour,
+		ReminderInterval: 5 * time.Minute,
+
+		SMTPPort:       587,
+		SMTPTLS:        mail.TLSStartTLS,
+		OutboxInterval: time.Minute,
+
+		WebhookInterval: 5 * time.Second,
+
+		LogLevel:  "info",
+		LogFormat: logging.FormatText,
+
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       10 * time.Minute,
+		WriteTimeout:      10 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+
+		HSTSMaxAge: 180 * 24 * time.Hour,
+	}
+}
+
+// Load builds the configuration from the defaults, the YAML file at path (if
+// path is not empty) and the environment. Flags are applied by the caller
+// afterwards, followed by Validate.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.UnmarshalWithOptions(raw, cfg, yaml.DisallowUnknownField()); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) applyEnv() error {
+	var errs []error
+
+	setString := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			*dst = v
+		}
+	}
+	setString("ENV", &cfg.Env)
+	setString("DATA_DIR", &cfg.DataDir)
+	setString("STORAGE_DIR", &cfg.StorageDir)
+	setString("CELERIX_NAMESPACE", &cfg.Namespace)
+	setString("ADMIN_SECRET", &cfg.AdminSecret)
+	setString("DEV_SERVER_URL", &cfg.DevServerURL)
+	setString("LOG_LEVEL", &cfg.LogLevel)
+	setString("LOG_FORMAT", &cfg.LogFormat)
+	setString("TLS_CERT_FILE", &cfg.TLSCertFile)
+	setString("TLS_KEY_FILE", &cfg.TLSKeyFile)
+	setString("API_ALIAS_SUNSET", &cfg.APIAliasSunset)
+	setString("SMTP_HOST", &cfg.SMTPHost)
+	setString("SMTP_USERNAME", &cfg.SMTPUsername)
+	setString("SMTP_PASSWORD", &cfg.SMTPPassword)
+	setString("SMTP_FROM", &cfg.SMTPFrom)
+	setString("SMTP_TLS", &cfg.SMTPTLS)
+
+	setInt := func(name string, dst *int) {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, v))
+			}
+			*dst = n
+		}
+	}
+	setInt("PORT", &cfg.Port)
+	setInt("REDIRECT_PORT", &cfg.RedirectPort)
+	setInt("SMTP_PORT", &cfg.SMTPPort)
+	if v := os.Getenv("CORS_ORIGINS"); v != "" {
+		cfg.CORSOrigins = nil
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, o)
+			}
+		}
+	}
+	setDuration := func(name string, dst *time.Duration) {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration (e.g. 720h)", name, v))
+			}
+			*dst = d
+		}
+	}
+	setDuration("TRASH_RETENTION", &cfg.TrashRetention)
+	setDuration("DUE_SOON", &cfg.DueSoon)
+	setDuration("REMINDER_INTERVAL", &cfg.ReminderInterval)
+	setDuration("OUTBOX_INTERVAL", &cfg.OutboxInterval)
+	setDuration("WEBHOOK_INTERVAL", &cfg.WebhookInterval)
+	setDuration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+
+	return errors.Join(errs...)
+}
+
+// Validate checks every setting, fills in derived values and reports all
+// problems at once.
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	if cfg.Env != "dev" && cfg.Env != "production" {
+		errs = append(errs, fmt.Errorf("env: must be \"dev\" or \"production\", got %q", cfg.Env))
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port: must be between 1 and 65535, got %d", cfg.Port))
+	}
+
+	if cfg.DataDir == "" {
+		errs = append(errs, errors.New("data_dir: must not be empty"))
+	}
+	if cfg.StorageDir == "" {
+		cfg.StorageDir = filepath.Join(cfg.DataDir, "uploads")
+	}
+
+	if cfg.Namespace == "" {
+		errs = append(errs, errors.New("namespace: required, set CELERIX_NAMESPACE or namespace in the config file"))
+	} else if ns, err := uuid.Parse(cfg.Namespace); err != nil {
+		errs = append(errs, fmt.Errorf("namespace: %q is not a UUID", cfg.Namespace))
+	} else if ns == uuid.Nil {
+		errs = append(errs, errors.New("namespace: must not be the nil UUID, generate one with uuidgen"))
+	} else {
+		cfg.CelerixNamespace = ns
+	}
+
+	if cfg.TrashRetention <= 0 {
+		errs = append(errs, fmt.Errorf("trash_retention: must be positive, got %s", cfg.TrashRetention))
+	}
+	if cfg.DueSoon <= 0 {
+		errs = append(errs, fmt.Errorf("due_soon: must be positive, got %s", cfg.DueSoon))
+	}
+	if cfg.ReminderInterval <= 0 {
+		errs = append(errs, fmt.Errorf("reminder_interval: must be positive, got %s", cfg.ReminderInterval))
+	}
+	if cfg.WebhookInterval <= 0 {
+		errs = append(errs, fmt.Errorf("webhook_interval: must be positive, got %s", cfg.WebhookInterval))
+	}
+	if cfg.MailEnabled() {
+		if cfg.SMTPPort < 1 || cfg.SMTPPort > 65535 {
+			errs = append(errs, fmt.Errorf("smtp_port: must be between 1 and 65535, got %d", cfg.SMTPPort))
+		}
+		if addr, err := netmail.ParseAddress(cfg.SMTPFrom); err != nil || addr.Name != "" {
+			errs = append(errs, fmt.Errorf("smtp_from: required with smtp_host, must be a plain email address, got %q", cfg.SMTPFrom))
+		}
+		switch cfg.SMTPTLS {
+		case mail.TLSStartTLS, mail.TLSImplicit, mail.TLSNone:
+		default:
+			errs = append(errs, fmt.Errorf("smtp_tls: must be starttls, tls or none, got %q", cfg.SMTPTLS))
+		}
+		if cfg.OutboxInterval <= 0 {
+			errs = append(errs, fmt.Errorf("outbox_interval: must be positive, got %s", cfg.OutboxInterval))
+		}
+	}
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_header_timeout", cfg.ReadHeaderTimeout},
+		{"read_timeout", cfg.ReadTimeout},
+		{"write_timeout", cfg.WriteTimeout},
+		{"idle_timeout", cfg.IdleTimeout},
+	} {
+		if t.d < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %s", t.name, t.d))
+		}
+	}
+	if level, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: must be debug, info, warn or error, got %q", cfg.LogLevel))
+	} else {
+		cfg.Level = level
+	}
+	if cfg.LogFormat != logging.FormatText && cfg.LogFormat != logging.FormatJSON {
+		errs = append(errs, fmt.Errorf("log_format: must be text or json, got %q", cfg.LogFormat))
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file, tls_key_file: set both or neither"))
+	}
+	for _, f := range []struct{ name, path string }{
+		{"tls_cert_file", cfg.TLSCertFile},
+		{"tls_key_file", cfg.TLSKeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+	if cfg.RedirectPort != 0 {
+		switch {
+		case !cfg.TLSEnabled():
+			errs = append(errs, errors.New("redirect_port: requires tls_cert_file and tls_key_file"))
+		case cfg.RedirectPort < 1 || cfg.RedirectPort > 65535:
+			errs = append(errs, fmt.Errorf("redirect_port: must be between 1 and 65535, got %d", cfg.RedirectPort))
+		case cfg.RedirectPort == cfg.Port:
+			errs = append(errs, errors.New("redirect_port: must differ from port"))
+		}
+	}
+	if cfg.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("hsts_max_age: must not be negative, got %s", cfg.HSTSMaxAge))
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout: must be positive, got %s", cfg.ShutdownTimeout))
+	}
+
+	if cfg.Env == "dev" {
+		if u, err := url.Parse(cfg.DevServerURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("dev_server_url: %q is not an absolute URL", cfg.DevServerURL))
+		} else {
+			// The dev server may also be opened directly instead of through the proxy
+			cfg.CORSOrigins = append(cfg.CORSOrigins, u.Scheme+"://"+u.Host)
+		}
+	}
+
+	if cfg.APIAliasSunset != "" {
+		if t, err := time.Parse(time.DateOnly, cfg.APIAliasSunset); err != nil {
+			errs = append(errs, fmt.Errorf("api_alias_sunset: %q is not a date (YYYY-MM-DD)", cfg.APIAliasSunset))
+		} else {
+			cfg.AliasSunset = t
+		}
+	}
+
+	if len(cfg.CORS) == 0 {
+		cfg.CORS = cors.Defaults(cfg.CORSOrigins)
+	}
+	for i := range cfg.CORS {
+		if err := cfg.CORS[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("cors[%d]: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EnsureDirs creates the data and storage directories.
+func (cfg *Config) EnsureDirs() error {
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	if err := os.MkdirAll(cfg.StorageDir, 0755); err != nil {
+		return fmt.Errorf("creating storage directory: %w", err)
+	}
+	return nil
+}
+
+// Addr is the listen address for the HTTP server.
+func (cfg *Config) Addr() string {
+	return ":" + strconv.Itoa(cfg.Port)
+}
+
+// TLSEnabled reports whether the server listens with HTTPS.
+func (cfg *Config) TLSEnabled() bool {
+	return cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
+}
+
+// MailEnabled reports whether notification emails are sent.
+func (cfg *Config) MailEnabled() bool {
+	return cfg.SMTPHost != ""
+}
+
+// SMTPAddr is the host:port of the SMTP server.
+func (cfg *Config) SMTPAddr() string {
+	return net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+port: 9000
+data_dir: /srv/flow
+namespace: c01e6180-2026-4d21-828a-7239842a2222
+trash_retention: 48h
+due_soon: 2h
+api_alias_sunset: 2027-06-30
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("REMINDER_INTERVAL", "30s")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_FROM", "flow@example.com")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9100 {
+		t.Errorf("Expected env to override the file port, got %d", cfg.Port)
+	}
+	if cfg.TrashRetention != 48*time.Hour {
+		t.Errorf("Expected trash retention 48h, got %s", cfg.TrashRetention)
+	}
+	if cfg.DueSoon != 2*time.Hour || cfg.ReminderInterval != 30*time.Second {
+		t.Errorf("Expected due soon 2h and reminder interval 30s, got %s and %s", cfg.DueSoon, cfg.ReminderInterval)
+	}
+	if !cfg.MailEnabled() || cfg.SMTPAddr() != "smtp.example.com:587" {
+		t.Errorf("Expected mail via smtp.example.com:587, got %q", cfg.SMTPAddr())
+	}
+	if cfg.StorageDir != filepath.Join("/srv/flow", "uploads") {
+		t.Errorf("Expected storage dir below data dir, got %s", cfg.StorageDir)
+	}
+	if cfg.CelerixNamespace.String() != "c01e6180-2026-4d21-828a-7239842a2222" {
+		t.Errorf("Expected parsed namespace, got %s", cfg.CelerixNamespace)
+	}
+	if want := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC); !cfg.AliasSunset.Equal(want) {
+		t.Errorf("Expected alias sunset %s, got %s", want, cfg.AliasSunset)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "prot: 8080\n")
+	if _, err := Load(path); err == nil {
+		t.Fatal("Expected an error for an unknown key")
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Env = "staging"
+	cfg.Port = 0
+	cfg.Namespace = "not-a-uuid"
+	cfg.APIAliasSunset = "30.06.2027"
+	cfg.SMTPHost = "smtp.example.com"
+	cfg.SMTPFrom = "Flow <flow@example.com>"
+	cfg.SMTPTLS = "ssl"
+	cfg.WebhookInterval = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"env:", "port:", "namespace:", "api_alias_sunset:", "smtp_from:", "smtp_tls:", "webhook_interval:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestValidateRejectsNilNamespace(t *testing.T) {
+	cfg := Default()
+	cfg.Namespace = "00000000-0000-0000-0000-000000000000"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "namespace:") {
+		t.Fatalf("Expected the nil namespace to be rejected, got %v", err)
+	}
+}
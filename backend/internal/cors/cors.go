@@ -0,0 +1,261 @@
+// Package cors implements a Cross-Origin Resource Sharing middleware with an
+// explicit origin allowlist and separate policies per route group.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnyOrigin allows every origin. It cannot be combined with credentials.
+const AnyOrigin = "*"
+
+// Policy is the CORS policy of every route below PathPrefix.
+type Policy struct {
+	PathPrefix       string        `yaml:"path_prefix"`
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// APIHeaders are the request headers the frontend sends to the API.
+var APIHeaders = []string{
+	"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization",
+	"Accept", "Origin", "Cache-Control", "X-Requested-With", "X-Client-ID", "X-Admin-Secret",
+}
+
+// Defaults returns the built-in policies: public download links may be
+// fetched from anywhere without credentials, the rest of the API only from
+// the given origins.
+func Defaults(origins []string) []Policy {
+	download := Policy{
+		PathPrefix:     "/api/v1/download",
+		AllowedOrigins: []string{AnyOrigin},
+		AllowedMethods: []string{http.MethodGet, http.MethodHead},
+		ExposedHeaders: []string{"Content-Disposition", "Content-Length"},
+		MaxAge:         time.Hour,
+	}
+	// Download links handed out before /api/v1 point at the unversioned alias
+	legacyDownload := download
+	legacyDownload.PathPrefix = "/api/download"
+
+	return []Policy{
+		download,
+		legacyDownload,
+		{
+			PathPrefix:       "/api",
+			AllowedOrigins:   origins,
+			AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+			AllowedHeaders:   APIHeaders,
+			ExposedHeaders:   []string{"Content-Disposition", "Deprecation", "Sunset", "Link"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		},
+	}
+}
+
+// Validate reports every problem with the policy.
+func (p *Policy) Validate() error {
+	var errs []error
+	if !strings.HasPrefix(p.PathPrefix, "/") {
+		errs = append(errs, fmt.Errorf("path_prefix: must start with /, got %q", p.PathPrefix))
+	}
+	for _, o := range p.AllowedOrigins {
+		if o == AnyOrigin {
+			if p.AllowCredentials {
+				errs = append(errs, errors.New("allowed_origins: * cannot be combined with allow_credentials"))
+			}
+			continue
+		}
+		if normalizeOrigin(o) == "" {
+			errs = append(errs, fmt.Errorf("allowed_origins: %q is not an origin like https://example.com", o))
+		}
+	}
+	if p.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max_age: must not be negative, got %s", p.MaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeOrigin lowercases scheme and host and returns "" for anything
+// that is not a bare scheme://host[:port].
+func normalizeOrigin(o string) string {
+	u, err := url.Parse(o)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+// compiled is a policy prepared for matching requests.
+type compiled struct {
+	prefix      string
+	anyOrigin   bool
+	origins     map[string]bool
+	methods     map[string]bool
+	headers     map[string]bool
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+func compile(p Policy) *compiled {
+	c := &compiled{
+		prefix:        strings.TrimSuffix(p.PathPrefix, "/"),
+		origins:       make(map[string]bool),
+		methods:       make(map[string]bool),
+		headers:       make(map[string]bool),
+		credentials:   p.AllowCredentials,
+		exposeHeaders: strings.Join(p.ExposedHeaders, ", "),
+	}
+	for _, o := range p.AllowedOrigins {
+		if o == AnyOrigin {
+			c.anyOrigin = true
+		} else {
+			c.origins[normalizeOrigin(o)] = true
+		}
+	}
+
+	methods := []string{http.MethodOptions}
+	c.methods[http.MethodOptions] = true
+	for _, m := range p.AllowedMethods {
+		m = strings.ToUpper(m)
+		if !c.methods[m] {
+			c.methods[m] = true
+			methods = append(methods, m)
+		}
+	}
+	c.allowMethods = strings.Join(methods, ", ")
+
+	for _, h := range p.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	c.allowHeaders = strings.Join(p.AllowedHeaders, ", ")
+
+	if p.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(p.MaxAge / time.Second))
+	}
+	return c
+}
+
+func (c *compiled) matches(path string) bool {
+	return path == c.prefix || strings.HasPrefix(path, c.prefix+"/") || c.prefix == ""
+}
+
+// allowOrigin returns the value for Access-Control-Allow-Origin, or "" when
+// the origin is not allowed.
+func (c *compiled) allowOrigin(origin string) string {
+	if c.anyOrigin {
+		return AnyOrigin
+	}
+	if c.origins[normalizeOrigin(origin)] {
+		return origin
+	}
+	return ""
+}
+
+func (c *compiled) allowsHeaders(requested string) bool {
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !c.headers[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware applies the policy with the longest matching path prefix to
+// every request. Requests outside all policies get no CORS headers.
+// Preflight requests are answered directly; a disallowed preflight gets a 204
+// without CORS headers, which the browser treats as a refusal.
+func Middleware(policies []Policy) gin.HandlerFunc {
+	list := make([]*compiled, 0, len(policies))
+	for _, p := range policies {
+		list = append(list, compile(p))
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i].prefix) > len(list[j].prefix)
+	})
+
+	return func(c *gin.Context) {
+		var policy *compiled
+		for _, p := range list {
+			if p.matches(c.Request.URL.Path) {
+				policy = p
+				break
+			}
+		}
+		if policy == nil {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Responses differ per origin unless every origin gets the same answer
+		if !policy.anyOrigin {
+			h.Add("Vary", "Origin")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		allowed := ""
+		if origin != "" {
+			allowed = policy.allowOrigin(origin)
+		}
+
+		if preflight {
+			method := strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+			if allowed != "" && policy.methods[method] && policy.allowsHeaders(c.GetHeader("Access-Control-Request-Headers")) {
+				h.Set("Access-Control-Allow-Origin", allowed)
+				h.Set("Access-Control-Allow-Methods", policy.allowMethods)
+				if policy.allowHeaders != "" {
+					h.Set("Access-Control-Allow-Headers", policy.allowHeaders)
+				}
+				if policy.credentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if policy.maxAge != "" {
+					h.Set("Access-Control-Max-Age", policy.maxAge)
+				}
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Origin", allowed)
+			if policy.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if policy.exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", policy.exposeHeaders)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,117 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(Defaults([]string{"https://app.example.com"})))
+	r.GET("/api/files", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/download/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/v1/download/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func request(r *gin.Engine, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAllowlistedOrigin(t *testing.T) {
+	r := setupRouter()
+
+	w := request(r, "GET", "/api/files", map[string]string{"Origin": "https://app.example.com"})
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected origin to be echoed, got %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Expected credentials to be allowed for the API")
+	}
+	if w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", w.Header().Get("Vary"))
+	}
+
+	w = request(r, "GET", "/api/files", map[string]string{"Origin": "https://evil.example.com"})
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers for a foreign origin, got %q", got)
+	}
+	if w.Header().Get("Vary") != "Origin" {
+		t.Error("Expected Vary: Origin on refused responses too")
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	r := setupRouter()
+
+	w := request(r, "OPTIONS", "/api/files", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "DELETE",
+		"Access-Control-Request-Headers": "x-client-id, content-type",
+	})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Error("Expected preflight to be allowed")
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("Expected max age 600, got %q", w.Header().Get("Access-Control-Max-Age"))
+	}
+
+	w = request(r, "OPTIONS", "/api/files", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": "PATCH",
+	})
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected preflight for a disallowed method to be refused")
+	}
+
+	w = request(r, "OPTIONS", "/api/files", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Unknown",
+	})
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Expected preflight for a disallowed header to be refused")
+	}
+}
+
+func TestPublicDownloadPolicy(t *testing.T) {
+	r := setupRouter()
+
+	for _, path := range []string{"/api/v1/download/abc", "/api/download/abc"} {
+		w := request(r, "GET", path, map[string]string{"Origin": "https://anywhere.example.org"})
+		if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("Expected wildcard origin for %s, got %q", path, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected no credentials for %s", path)
+		}
+		if w.Header().Get("Vary") != "" {
+			t.Errorf("Expected no Vary for a wildcard policy, got %q", w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestValidateRejectsWildcardWithCredentials(t *testing.T) {
+	p := Policy{PathPrefix: "/api", AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := p.Validate(); err == nil {
+		t.Error("Expected wildcard with credentials to be rejected")
+	}
+
+	p = Policy{PathPrefix: "/api", AllowedOrigins: []string{"https://example.com/path"}}
+	if err := p.Validate(); err == nil {
+		t.Error("Expected an origin with a path to be rejected")
+	}
+}
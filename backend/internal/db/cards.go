@@ -0,0 +1,214 @@
+package db
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// AssigneeMe matches cards assigned to project users linked to the
+	// caller.
+	AssigneeMe = "me"
+	// AssigneeNone matches unassigned cards.
+	AssigneeNone = "none"
+	// PurposeNone matches cards in columns without a purpose.
+	PurposeNone = "none"
+)
+
+// BoardCard is a card with the board position it was found at. The board's
+// owner is shown by OwnerHandle and name, as in Membership.
+type BoardCard struct {
+	Owner         string     `json:"owner"`
+	OwnerName     string     `json:"ownerName"`
+	ProjectID     string     `json:"projectId,omitempty"`
+	ProjectName   string     `json:"projectName,omitempty"`
+	ColumnID      string     `json:"columnId"`
+	ColumnTitle   string     `json:"columnTitle"`
+	ColumnPurpose string     `json:"columnPurpose,omitempty"`
+	Card          KanbanCard `json:"card"`
+}
+
+// CardFilter selects cards; empty fields match every card. Assignee is a
+// nickname, AssigneeMe or AssigneeNone. Due dates are YYYY-MM-DD and
+// inclusive; cards without a due date never match a due date bound.
+type CardFilter struct {
+	Assignee   string
+	Priorities []string
+	DueBefore  string
+	DueAfter   string
+	ProjectID  string
+	Purposes   []string
+}
+
+func (f CardFilter) validate() error {
+	problems := map[string]string{}
+	for field, val := range map[string]string{"due_before": f.DueBefore, "due_after": f.DueAfter} {
+		if _, err := time.Parse(time.DateOnly, val); val != "" && err != nil {
+			problems[field] = "must be a date like 2006-01-02"
+		}
+	}
+	if len(problems) > 0 {
+		return Validation("Invalid card filter").WithDetails(problems)
+	}
+	return nil
+}
+
+// matches reports whether card, in col of a board whose project it belongs
+// to is project (nil if none), passes the filter for clientID.
+func (f CardFilter) matches(clientID string, col *KanbanColumn, card *KanbanCard, project *Project) bool {
+	switch f.Assignee {
+	case "":
+	case AssigneeNone:
+		if card.Assignee != "" {
+			return false
+		}
+	case AssigneeMe:
+		if card.Assignee == "" || project == nil {
+			return false
+		}
+		if user := project.userByNickname(card.Assignee); user == nil || user.ClientID != clientID {
+			return false
+		}
+	default:
+		if !strings.EqualFold(card.Assignee, f.Assignee) {
+			return false
+		}
+	}
+
+	if len(f.Priorities) > 0 && !slices.ContainsFunc(f.Priorities, func(p string) bool {
+		return strings.EqualFold(p, card.Priority)
+	}) {
+		return false
+	}
+
+	if f.DueBefore != "" || f.DueAfter != "" {
+		day, ok := dueDay(card.DueDate)
+		if !ok || (f.DueBefore != "" && day > f.DueBefore) || (f.DueAfter != "" && day < f.DueAfter) {
+			return false
+		}
+	}
+
+	if f.ProjectID != "" && card.ProjectID != f.ProjectID {
+		return false
+	}
+
+	if len(f.Purposes) > 0 {
+		purpose := col.Purpose
+		if purpose == "" {
+			purpose = PurposeNone
+		}
+		if !slices.Contains(f.Purposes, purpose) {
+			return false
+		}
+	}
+	return true
+}
+
+// dueDay returns the day of a card's due date as YYYY-MM-DD.
+func dueDay(due string) (string, bool) {
+	t, _, ok := parseDue(due, time.UTC)
+	if !ok {
+		return "", false
+	}
+	return t.Format(time.DateOnly), true
+}
+
+// parseDue reads a card's due date. The frontend stores plain dates, read
+// in loc, but full timestamps are accepted too; dateOnly tells them apart.
+func parseDue(due string, loc *time.Location) (t time.Time, dateOnly, ok bool) {
+	if t, err := time.ParseInLocation(time.DateOnly, due, loc); err == nil {
+		return t, true, true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, due, loc); err == nil {
+			return t, false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// FindCards lists the cards of every board clientID can access that pass
+// the filter: all cards of the client's own board, and on other boards the
+// cards of projects the client is linked to. Cards due soonest come first;
+// cards without a due date come last, newest first.
+func FindCards(s CelerixStore, clientID string, f CardFilter) ([]BoardCard, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	own, err := GetProjects(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := Memberships(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Projects visible on each board; the own board is visible as a whole
+	scopes := map[string]map[string]*Project{clientID: {}}
+	for i := range own.Projects {
+		scopes[clientID][own.Projects[i].ID] = &own.Projects[i]
+	}
+	for i := range memberships {
+		m := &memberships[i]
+		if m.OwnerID == clientID {
+			continue
+		}
+		if scopes[m.OwnerID] == nil {
+			scopes[m.OwnerID] = map[string]*Project{}
+		}
+		scopes[m.OwnerID][m.Project.ID] = &m.Project
+	}
+	owners := make([]string, 0, len(scopes))
+	for owner := range scopes {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	cards := []BoardCard{}
+	for _, owner := range owners {
+		board, _, err := GetKanban(s, owner)
+		if err != nil {
+			return nil, err
+		}
+		scope := scopes[owner]
+		handle, name := OwnerHandle(owner), ownerName(s, owner)
+		board.Cards(func(col *KanbanColumn, card *KanbanCard) {
+			project := scope[card.ProjectID]
+			if owner != clientID && project == nil {
+				return
+			}
+			if !f.matches(clientID, col, card, project) {
+				return
+			}
+			found := BoardCard{
+				Owner:         handle,
+				OwnerName:     name,
+				ProjectID:     card.ProjectID,
+				ColumnID:      col.ID,
+				ColumnTitle:   col.Title,
+				ColumnPurpose: col.Purpose,
+				Card:          *card,
+			}
+			if project != nil {
+				found.ProjectName = project.Name
+			}
+			cards = append(cards, found)
+		})
+	}
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		a, b := cards[i].Card, cards[j].Card
+		if (a.DueDate == "") != (b.DueDate == "") {
+			return a.DueDate != ""
+		}
+		if a.DueDate != b.DueDate {
+			return a.DueDate < b.DueDate
+		}
+		return a.CreatedAt > b.CreatedAt
+	})
+	return cards, nil
+}
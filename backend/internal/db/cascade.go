@@ -0,0 +1,355 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+const (
+	KanbanKey = "kanban"
+	// TransferredKeyPrefix namespaces generic keys that already existed on the
+	// target of an ownership transfer: "transferred:<source-id>:<key>".
+	TransferredKeyPrefix = "transferred:"
+)
+
+// GetPersonaKeys lists every flow key stored for a persona. A persona without
+// any flow data yields an empty map.
+func GetPersonaKeys(s CelerixStore, persona string) (map[string]any, error) {
+	appStore, err := s.GetAppStore(persona, AppID)
+	if err != nil {
+		if isMissing(err) {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+	return appStore, nil
+}
+
+// ClientFiles returns every live and trashed file record owned by a client.
+func ClientFiles(s CelerixStore, id string) ([]FileRecord, error) {
+	keys, err := GetPersonaKeys(s, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileRecord
+	for k := range keys {
+		if strings.HasPrefix(k, FileKeyPrefix) || strings.HasPrefix(k, TrashFileKeyPrefix) {
+			r, err := sdk.Get[FileRecord](s, id, AppID, k)
+			if err == nil {
+				files = append(files, r)
+			}
+		}
+	}
+	return files, nil
+}
+
+// TrashClientCascade moves a client and all of their live files into the
+// trash. The client is flagged so that purging it also removes the rest of
+// their data (kanban, generic keys and blobs).
+func TrashClientCascade(s CelerixStore, id string, deletedAt int64) error {
+	client, err := GetClient(s, id)
+	if err != nil {
+		return err
+	}
+
+	keys, err := GetPersonaKeys(s, id)
+	if err != nil {
+		return err
+	}
+
+	tx := NewTx(s)
+	err = func() error {
+		for k := range keys {
+			if !strings.HasPrefix(k, FileKeyPrefix) {
+				continue
+			}
+			r, err := sdk.Get[FileRecord](s, id, AppID, k)
+			if err != nil {
+				return err
+			}
+			r.DeletedAt = deletedAt
+			r.TrashedWith = id
+			r.OwnerName = ""
+			if err := tx.Set(id, TrashFileKeyPrefix+r.ID, r); err != nil {
+				return err
+			}
+			if err := tx.Delete(id, k); err != nil {
+				return err
+			}
+		}
+
+		client.DeletedAt = deletedAt
+		client.CascadeDelete = true
+		if err := tx.Set(SystemPersona, TrashClientKeyPrefix+id, client); err != nil {
+			return err
+		}
+		return tx.Delete(SystemPersona, ClientKeyPrefix+id)
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// TrashClientWithTransfer hands every file, the kanban board and all generic
+// keys of a client over to targetID, then moves the (now empty) client into
+// the trash. Kanban columns are appended to the target's board; generic keys
+// that already exist on the target are kept under TransferredKeyPrefix, so
+// cards keep only references to projects the target ends up with. The
+// client's email preferences and notifications are dropped.
+func TrashClientWithTransfer(s CelerixStore, id, targetID string, deletedAt int64) error {
+	if id == targetID {
+		return Validation("Cannot transfer ownership to the deleted client")
+	}
+	client, err := GetClient(s, id)
+	if err != nil {
+		return err
+	}
+	if _, err := GetClient(s, targetID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return NotFound("Target client not found")
+		}
+		return err
+	}
+
+	keys, err := GetPersonaKeys(s, id)
+	if err != nil {
+		return err
+	}
+
+	tx := NewTx(s)
+	err = func() error {
+		for k, val := range keys {
+			switch {
+			case strings.HasPrefix(k, FileKeyPrefix) || strings.HasPrefix(k, TrashFileKeyPrefix):
+				r, err := sdk.Get[FileRecord](s, id, AppID, k)
+				if err != nil {
+					return err
+				}
+				r.OwnerID = targetID
+				r.OwnerName = ""
+				if err := tx.Set(targetID, k, r); err != nil {
+					return err
+				}
+				if err := tx.Delete(id, k); err != nil {
+					return err
+				}
+
+			case PersonalKey(k):
+				if err := tx.Delete(id, k); err != nil {
+					return err
+				}
+
+			case k == KanbanKey:
+				board, err := transferredBoard(s, id, targetID, val)
+				if err != nil {
+					return err
+				}
+				merged, err := mergeKanban(s, targetID, board)
+				if err != nil {
+					return err
+				}
+				if err := tx.Set(targetID, k, merged); err != nil {
+					return err
+				}
+				if err := tx.Delete(id, k); err != nil {
+					return err
+				}
+
+			default:
+				dst := k
+				if _, err := s.Get(targetID, AppID, k); err == nil {
+					dst = TransferredKeyPrefix + id + ":" + k
+				}
+				if err := tx.Set(targetID, dst, val); err != nil {
+					return err
+				}
+				if err := tx.Delete(id, k); err != nil {
+					return err
+				}
+			}
+		}
+
+		client.DeletedAt = deletedAt
+		if err := tx.Set(SystemPersona, TrashClientKeyPrefix+id, client); err != nil {
+			return err
+		}
+		return tx.Delete(SystemPersona, ClientKeyPrefix+id)
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// transferredBoard returns the board val of client id as it joins the board
+// of targetID. The target keeps its own projects if it has any, so cards of
+// the client lose references to projects the target does not have.
+func transferredBoard(s CelerixStore, id, targetID string, val any) (KanbanBoard, error) {
+	board, err := DecodeKanban(val)
+	if err != nil {
+		return KanbanBoard{}, err
+	}
+	own, err := GetProjects(s, id)
+	if err != nil {
+		return KanbanBoard{}, err
+	}
+	final := own.Projects
+	if _, err := s.Get(targetID, AppID, ProjectsKey); err == nil {
+		target, err := GetProjects(s, targetID)
+		if err != nil {
+			return KanbanBoard{}, err
+		}
+		final = target.Projects
+	} else if !isMissing(err) {
+		return KanbanBoard{}, err
+	}
+	if _, err := reconcileBoard(&board, own.Projects, final, CascadeOptions{Mode: CascadeClear}); err != nil {
+		return KanbanBoard{}, err
+	}
+	return board, nil
+}
+
+// mergeKanban appends the columns of src to the board stored for persona.
+func mergeKanban(s CelerixStore, persona string, src any) (any, error) {
+	dst, err := sdk.Get[map[string]any](s, persona, AppID, KanbanKey)
+	if err != nil {
+		// The target has no board yet, take the source board as is
+		return src, nil
+	}
+
+	var srcBoard map[string]any
+	raw, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &srcBoard); err != nil {
+		return nil, fmt.Errorf("unexpected kanban format: %w", err)
+	}
+
+	dstCols, _ := dst["columns"].([]any)
+	srcCols, _ := srcBoard["columns"].([]any)
+	dst["columns"] = append(dstCols, srcCols...)
+	return dst, nil
+}
+
+// RestoreClient restores a trashed client together with the files that were
+// trashed along with it.
+func RestoreClient(s CelerixStore, id string) error {
+	client, err := GetTrashedClient(s, id)
+	if err != nil {
+		return err
+	}
+	if _, err := GetClient(s, id); err == nil {
+		return Conflict("Client %s already exists", id)
+	}
+
+	keys, err := GetPersonaKeys(s, id)
+	if err != nil {
+		return err
+	}
+
+	tx := NewTx(s)
+	err = func() error {
+		for k := range keys {
+			if !strings.HasPrefix(k, TrashFileKeyPrefix) {
+				continue
+			}
+			r, err := sdk.Get[FileRecord](s, id, AppID, k)
+			if err != nil {
+				return err
+			}
+			if r.TrashedWith != id {
+				continue
+			}
+			r.DeletedAt = 0
+			r.TrashedWith = ""
+			if err := tx.Set(id, FileKeyPrefix+r.ID, r); err != nil {
+				return err
+			}
+			if err := tx.Delete(id, k); err != nil {
+				return err
+			}
+		}
+
+		client.DeletedAt = 0
+		client.CascadeDelete = false
+		if err := tx.Set(SystemPersona, ClientKeyPrefix+id, client); err != nil {
+			return err
+		}
+		return tx.Delete(SystemPersona, TrashClientKeyPrefix+id)
+	}()
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// PurgeClientData deletes every flow key of a client's persona, their
+// webhooks with the deliveries, their inbound hooks, their API tokens and
+// their trashed client record through tx.
+func PurgeClientData(tx *Tx, id string) error {
+	keys, err := GetPersonaKeys(tx.Store(), id)
+	if err != nil {
+		return err
+	}
+	for k := range keys {
+		if err := tx.Delete(id, k); err != nil {
+			return err
+		}
+	}
+
+	hooks, err := ListWebhooks(tx.Store(), id, false)
+	if err != nil {
+		return err
+	}
+	for _, w := range hooks {
+		deliveries, err := ListDeliveries(tx.Store(), w.ID)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			if err := tx.Delete(SystemPersona, DeliveryKeyPrefix+d.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(SystemPersona, WebhookKeyPrefix+w.ID); err != nil {
+			return err
+		}
+	}
+
+	inbound, err := ListInboundHooks(tx.Store(), id)
+	if err != nil {
+		return err
+	}
+	for _, hook := range inbound {
+		if err := tx.Delete(SystemPersona, InboundHookKeyPrefix+hook.ID); err != nil {
+			return err
+		}
+	}
+
+	tokens, err := ListAPITokens(tx.Store(), id)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := tx.Delete(SystemPersona, APITokenKeyPrefix+t.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(SystemPersona, TrashClientKeyPrefix+id)
+}
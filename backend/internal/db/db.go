@@ -1,7 +1,7 @@
 package db
 
 import (
-	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -20,6 +20,8 @@ type FileRecord struct {
 	OwnerName    string `json:"owner_name"`
 	DownloadLink string `json:"download_link"`
 	IsPublic     bool   `json:"is_public"`
+	DeletedAt    int64  `json:"deleted_at,omitempty"`
+	TrashedWith  string `json:"trashed_with,omitempty"`
 }
 
 type ListFilesOptions struct {
@@ -35,20 +37,48 @@ type FileListResponse struct {
 }
 
 type ClientRecord struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	RecoveryCode string `json:"recovery_code"`
-	LastActive   int64  `json:"last_active"`
-	IsAdmin      bool   `json:"is_admin"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	RecoveryCode  string `json:"recovery_code"`
+	LastActive    int64  `json:"last_active"`
+	IsAdmin       bool   `json:"is_admin"`
+	DeletedAt     int64  `json:"deleted_at,omitempty"`
+	CascadeDelete bool   `json:"cascade_delete,omitempty"`
 }
 
 const (
-	AppID           = "flow"
-	FileKeyPrefix   = "file:"
-	ClientKeyPrefix = "client:"
-	SystemPersona   = sdk.SystemPersona
+	AppID                = "flow"
+	FileKeyPrefix        = "file:"
+	ClientKeyPrefix      = "client:"
+	TrashFileKeyPrefix   = "trash:file:"
+	TrashClientKeyPrefix = "trash:client:"
+	SystemPersona        = sdk.SystemPersona
 )
 
+// personalKeys hold settings and state of the client itself. They never pass
+// to another client, not even with a transfer of ownership.
+var personalKeys = []string{EmailPrefsKey, NotificationsKey, RemindedKey}
+
+// PersonalKey reports whether key holds settings or state of the client
+// itself, which are neither transferred nor imported.
+func PersonalKey(key string) bool {
+	return slices.Contains(personalKeys, key)
+}
+
+// ServerManaged reports whether key is written by the server only and must
+// not be set through the generic store.
+func ServerManaged(key string) bool {
+	if PersonalKey(key) {
+		return true
+	}
+	for _, prefix := range []string{FileKeyPrefix, "trash:", TransferredKeyPrefix} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func SaveFileRecord(s CelerixStore, record FileRecord) error {
 	persona := record.OwnerID
 	if persona == "" {
@@ -101,12 +131,12 @@ func DeleteFileRecord(s CelerixStore, id string) error {
 func GetFileRecord(s CelerixStore, id string) (*FileRecord, error) {
 	_, personaID, err := s.GetGlobal(AppID, FileKeyPrefix+id)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err, "File not found")
 	}
 
 	record, err := sdk.Get[FileRecord](s, personaID, AppID, FileKeyPrefix+id)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err, "File not found")
 	}
 
 	// Fetch owner name
@@ -247,13 +277,13 @@ func DeleteClient(s CelerixStore, id string) error {
 func GetClient(s CelerixStore, id string) (*ClientRecord, error) {
 	client, err := sdk.Get[ClientRecord](s, SystemPersona, AppID, ClientKeyPrefix+id)
 	if err != nil {
-		return nil, err
+		return nil, storeError(err, "Client not found")
 	}
 	return &client, nil
 }
 
 func GetClientByRecoveryCode(s CelerixStore, code string) (*ClientRecord, error) {
-	appStore, err := s.GetAppStore(SystemPersona, AppID)
+	appStore, err := GetPersonaKeys(s, SystemPersona)
 	if err != nil {
 		return nil, err
 	}
@@ -266,11 +296,11 @@ func GetClientByRecoveryCode(s CelerixStore, code string) (*ClientRecord, error)
 			}
 		}
 	}
-	return nil, fmt.Errorf("client not found")
+	return nil, NotFound("Client not found")
 }
 
 func ListClients(s CelerixStore) ([]ClientRecord, error) {
-	appStore, err := s.GetAppStore(SystemPersona, AppID)
+	appStore, err := GetPersonaKeys(s, SystemPersona)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// EmailPrefsKey holds the email preferences of a client.
+const EmailPrefsKey = "email_prefs"
+
+// EmailPrefs says which notifications a client wants by email. Every kind
+// is off until the client opts in.
+type EmailPrefs struct {
+	Email        string `json:"email"`
+	DueReminders bool   `json:"due_reminders"`
+	Assignments  bool   `json:"assignments"`
+	ShareLinks   bool   `json:"share_links"`
+}
+
+// Wants reports whether notifications of the type are to be emailed.
+func (p EmailPrefs) Wants(notificationType string) bool {
+	if p.Email == "" {
+		return false
+	}
+	switch notificationType {
+	case NotificationDueSoon, NotificationOverdue:
+		return p.DueReminders
+	case NotificationAssigned:
+		return p.Assignments
+	case NotificationDownloaded:
+		return p.ShareLinks
+	}
+	return false
+}
+
+// GetEmailPrefs returns the email preferences of a client, all off if it
+// has none.
+func GetEmailPrefs(s CelerixStore, clientID string) (EmailPrefs, error) {
+	prefs, err := sdk.Get[EmailPrefs](s, clientID, AppID, EmailPrefsKey)
+	if isMissing(err) {
+		return EmailPrefs{}, nil
+	}
+	return prefs, err
+}
+
+// SaveEmailPrefs validates and stores the email preferences of a client.
+// Opting in to anything requires an address.
+func SaveEmailPrefs(s CelerixStore, clientID string, prefs EmailPrefs) (EmailPrefs, error) {
+	prefs.Email = strings.TrimSpace(prefs.Email)
+	if prefs.Email != "" {
+		addr, err := mail.ParseAddress(prefs.Email)
+		if err != nil || addr.Name != "" {
+			return EmailPrefs{}, Validation("Invalid email preferences").
+				WithDetails(map[string]string{"email": "must be a plain email address"})
+		}
+		prefs.Email = addr.Address
+	} else if prefs.DueReminders || prefs.Assignments || prefs.ShareLinks {
+		return EmailPrefs{}, Validation("Invalid email preferences").
+			WithDetails(map[string]string{"email": "is required to receive emails"})
+	}
+	if err := s.Set(clientID, AppID, EmailPrefsKey, prefs); err != nil {
+		return EmailPrefs{}, err
+	}
+	return prefs, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds. Every error returned from this package that callers are
+// expected to handle wraps one of them, so they can be told apart with
+// errors.Is instead of comparing messages.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrForbidden  = errors.New("forbidden")
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is an error of a known kind with a message fit for API clients and
+// optional structured details.
+type Error struct {
+	Kind    error
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newError(kind error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...any) *Error   { return newError(ErrNotFound, format, args...) }
+func Conflict(format string, args ...any) *Error   { return newError(ErrConflict, format, args...) }
+func Forbidden(format string, args ...any) *Error  { return newError(ErrForbidden, format, args...) }
+func Validation(format string, args ...any) *Error { return newError(ErrValidation, format, args...) }
+
+// WithDetails attaches structured details, e.g. the offending fields.
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+// Wrap keeps err as the underlying cause.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// isMissing reports whether err is the store's answer for a key, app or
+// persona that does not exist. The embedded engine and the remote client use
+// distinct error values with the same messages, so this is the one place
+// that has to look at the text.
+func isMissing(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	switch err.Error() {
+	case "key not found", "app not found", "persona not found":
+		return true
+	}
+	return strings.HasSuffix(err.Error(), ": key not found")
+}
+
+// storeError translates missing-data errors of the store into ErrNotFound
+// with the given message and leaves everything else alone.
+func storeError(err error, message string) error {
+	if isMissing(err) {
+		return NotFound("%s", message).Wrap(err)
+	}
+	return err
+}
+
+// GetValue reads a raw value of a persona. A missing key, app or persona is
+// reported as ErrNotFound.
+func GetValue(s CelerixStore, persona, key string) (any, error) {
+	val, err := s.Get(persona, AppID, key)
+	if err != nil {
+		return nil, storeError(err, "Key "+key+" not found")
+	}
+	return val, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+// flushKey is written and removed again to persist personas that have no
+// flow keys left.
+const flushKey = "_flush"
+
+type waiter interface {
+	Wait()
+}
+
+// Flush makes sure the on-disk state of an embedded store matches memory.
+//
+// The embedded engine persists every write from its own goroutine, so two
+// quick writes to the same persona may hit the disk in the wrong order. Flush
+// waits for pending writes and then rewrites each persona one at a time,
+// waiting in between, so the last snapshot written is the current one.
+// Remote stores persist synchronously and are left alone.
+func Flush(s CelerixStore) error {
+	w, ok := s.(waiter)
+	if !ok {
+		return nil
+	}
+	w.Wait()
+
+	data, err := s.DumpApp(AppID)
+	if err != nil {
+		return err
+	}
+
+	for persona, keys := range data {
+		rewritten := false
+		for k, v := range keys {
+			if err := s.Set(persona, AppID, k, v); err != nil {
+				return err
+			}
+			rewritten = true
+			break
+		}
+		w.Wait()
+
+		if !rewritten {
+			if err := s.Set(persona, AppID, flushKey, true); err != nil {
+				return err
+			}
+			w.Wait()
+			if err := s.Delete(persona, AppID, flushKey); err != nil {
+				return err
+			}
+			w.Wait()
+		}
+	}
+	return nil
+}
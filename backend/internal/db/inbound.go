@@ -0,0 +1,126 @@
+package db
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/google/uuid"
+)
+
+// InboundHookKeyPrefix prefixes inbound hooks in the system persona.
+const InboundHookKeyPrefix = "inbound_hook:"
+
+// InboundHook lets external systems add cards to the board of Owner by
+// posting to its URL with its token. Only a hash of the token is stored.
+// Column is the default column, by ID or title. Times are unix seconds.
+type InboundHook struct {
+	ID         string `json:"id"`
+	Owner      string `json:"owner"`
+	Name       string `json:"name"`
+	Column     string `json:"column,omitempty"`
+	TokenHash  string `json:"token_hash"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at,omitempty"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateInboundHook stores a new inbound hook of owner that accepts token.
+func CreateInboundHook(s CelerixStore, owner, name, column, token string, now time.Time) (InboundHook, error) {
+	hook := InboundHook{
+		ID:        uuid.NewString(),
+		Owner:     owner,
+		Name:      strings.TrimSpace(name),
+		Column:    strings.TrimSpace(column),
+		TokenHash: hashToken(token),
+		CreatedAt: now.Unix(),
+	}
+	if hook.Name == "" {
+		return InboundHook{}, Validation("Invalid inbound hook").WithDetails(map[string]string{"name": "is required"})
+	}
+	if err := s.Set(SystemPersona, AppID, InboundHookKeyPrefix+hook.ID, hook); err != nil {
+		return InboundHook{}, err
+	}
+	return hook, nil
+}
+
+func GetInboundHook(s CelerixStore, id string) (*InboundHook, error) {
+	hook, err := sdk.Get[InboundHook](s, SystemPersona, AppID, InboundHookKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "Inbound hook not found")
+	}
+	return &hook, nil
+}
+
+// ListInboundHooks returns the inbound hooks of a client, oldest first.
+func ListInboundHooks(s CelerixStore, owner string) ([]InboundHook, error) {
+	keys, err := GetPersonaKeys(s, SystemPersona)
+	if err != nil {
+		return nil, err
+	}
+	hooks := []InboundHook{}
+	for k := range keys {
+		if !strings.HasPrefix(k, InboundHookKeyPrefix) {
+			continue
+		}
+		hook, err := sdk.Get[InboundHook](s, SystemPersona, AppID, k)
+		if err == nil && hook.Owner == owner {
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		if hooks[i].CreatedAt != hooks[j].CreatedAt {
+			return hooks[i].CreatedAt < hooks[j].CreatedAt
+		}
+		return hooks[i].ID < hooks[j].ID
+	})
+	return hooks, nil
+}
+
+// inboundHookMu serializes recording a hook's use with deleting it, so a
+// request racing the delete cannot write the hook back.
+var inboundHookMu sync.Mutex
+
+func DeleteInboundHook(s CelerixStore, id string) error {
+	inboundHookMu.Lock()
+	defer inboundHookMu.Unlock()
+	err := s.Delete(SystemPersona, AppID, InboundHookKeyPrefix+id)
+	return storeError(err, "Inbound hook not found")
+}
+
+// AuthenticateInboundHook returns the inbound hook id if token is its
+// token, and records the use. A wrong token, or a hook of a client that is
+// not live, is reported like an unknown hook, so hook IDs cannot be probed.
+func AuthenticateInboundHook(s CelerixStore, id, token string, now time.Time) (*InboundHook, error) {
+	inboundHookMu.Lock()
+	defer inboundHookMu.Unlock()
+	hook, err := GetInboundHook(s, id)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(hook.TokenHash), []byte(hashToken(token))) != 1 {
+		return nil, NotFound("Inbound hook not found")
+	}
+	// Hooks of a trashed client stop working until the client is restored
+	if _, err := GetClient(s, hook.Owner); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, NotFound("Inbound hook not found")
+		}
+		return nil, err
+	}
+	hook.LastUsedAt = now.Unix()
+	if err := s.Set(SystemPersona, AppID, InboundHookKeyPrefix+hook.ID, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
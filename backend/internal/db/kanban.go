@@ -0,0 +1,275 @@
+package db
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	KanbanVersion       = "1.0.0"
+	KanbanColumnVersion = "1.0.0"
+	KanbanCardVersion   = "1.0.0"
+)
+
+// Priorities lists the priorities a card can have, lowest first.
+var Priorities = []string{"low", "medium", "high", "urgent"}
+
+type ChecklistItem struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	Completed bool   `json:"completed"`
+}
+
+// KanbanCard is a card of the board. ProjectID names a project of the same
+// persona and Assignee the nickname of a user of that project.
+type KanbanCard struct {
+	Version     string          `json:"version"`
+	ID          string          `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Color       string          `json:"color,omitempty"`
+	ProjectID   string          `json:"projectId,omitempty"`
+	Priority    string          `json:"priority,omitempty"`
+	DueDate     string          `json:"dueDate,omitempty"`
+	CreatedAt   int64           `json:"createdAt"`
+	Assignee    string          `json:"assignee,omitempty"`
+	Checklist   []ChecklistItem `json:"checklist,omitempty"`
+}
+
+type KanbanColumn struct {
+	Version string       `json:"version"`
+	ID      string       `json:"id"`
+	Title   string       `json:"title"`
+	Color   string       `json:"color,omitempty"`
+	Purpose string       `json:"purpose,omitempty"`
+	Cards   []KanbanCard `json:"cards"`
+}
+
+// KanbanBoard is the stored value under KanbanKey.
+type KanbanBoard struct {
+	Version string         `json:"version"`
+	Columns []KanbanColumn `json:"columns"`
+}
+
+// Cards calls fn for every card of the board; fn may modify the card.
+func (b *KanbanBoard) Cards(fn func(col *KanbanColumn, card *KanbanCard)) {
+	for i := range b.Columns {
+		col := &b.Columns[i]
+		for j := range col.Cards {
+			fn(col, &col.Cards[j])
+		}
+	}
+}
+
+func (b *KanbanBoard) normalize() {
+	if b.Version == "" {
+		b.Version = KanbanVersion
+	}
+	for i := range b.Columns {
+		col := &b.Columns[i]
+		if col.Version == "" {
+			col.Version = KanbanColumnVersion
+		}
+		for j := range col.Cards {
+			if col.Cards[j].Version == "" {
+				col.Cards[j].Version = KanbanCardVersion
+			}
+		}
+	}
+}
+
+// DecodeKanban reads a stored or submitted board.
+func DecodeKanban(val any) (KanbanBoard, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return KanbanBoard{}, err
+	}
+	var board KanbanBoard
+	if err := json.Unmarshal(raw, &board); err != nil {
+		return KanbanBoard{}, Validation("Kanban board has an unexpected format").Wrap(err)
+	}
+	if board.Columns == nil {
+		board.Columns = []KanbanColumn{}
+	}
+	for i := range board.Columns {
+		if board.Columns[i].Cards == nil {
+			board.Columns[i].Cards = []KanbanCard{}
+		}
+	}
+	return board, nil
+}
+
+// GetKanban returns the board of a persona. found is false if none is
+// stored yet.
+func GetKanban(s CelerixStore, persona string) (board KanbanBoard, found bool, err error) {
+	val, err := s.Get(persona, AppID, KanbanKey)
+	if isMissing(err) {
+		return KanbanBoard{Columns: []KanbanColumn{}}, false, nil
+	}
+	if err != nil {
+		return KanbanBoard{}, false, err
+	}
+	board, err = DecodeKanban(val)
+	return board, err == nil, err
+}
+
+// KanbanChanges describes what saving a board changed.
+type KanbanChanges struct {
+	// Assigned are notifications about cards newly assigned to project
+	// users linked to other clients, by client.
+	Assigned map[string][]Notification
+	Created  []CardChange
+	Moved    []CardChange
+	Deleted  []CardChange
+}
+
+// CardChange is a card that was added to, moved on or removed from a board.
+// The column is where the card is now, or was last for a removed card.
+type CardChange struct {
+	Card            KanbanCard `json:"card"`
+	ColumnID        string     `json:"columnId"`
+	ColumnTitle     string     `json:"columnTitle"`
+	FromColumnID    string     `json:"fromColumnId,omitempty"`
+	FromColumnTitle string     `json:"fromColumnTitle,omitempty"`
+}
+
+// SaveKanban stores the board of a persona after checking that its cards
+// reference existing projects and project users. References a card already
+// had in the stored board are not checked again, so boards with references
+// that broke before these checks existed can still be saved.
+func SaveKanban(s CelerixStore, persona string, board KanbanBoard) (KanbanChanges, error) {
+	boardMu.Lock()
+	defer boardMu.Unlock()
+
+	projects, err := GetProjects(s, persona)
+	if err != nil {
+		return KanbanChanges{}, err
+	}
+	prev, _, err := GetKanban(s, persona)
+	if err != nil {
+		prev = KanbanBoard{}
+	}
+
+	if dangling := danglingReferences(board, projects.Projects, prev); len(dangling) > 0 {
+		return KanbanChanges{}, Validation("Cards reference unknown projects or assignees").WithDetails(dangling)
+	}
+	board.normalize()
+	if err := s.Set(persona, AppID, KanbanKey, board); err != nil {
+		return KanbanChanges{}, err
+	}
+	return diffKanban(persona, projects.Projects, prev, board), nil
+}
+
+// diffKanban compares two versions of the board of owner.
+func diffKanban(owner string, projects []Project, prev, board KanbanBoard) KanbanChanges {
+	type placed struct {
+		col      *KanbanColumn
+		card     *KanbanCard
+		assignee string
+	}
+	before := map[string]placed{}
+	prev.Cards(func(col *KanbanColumn, card *KanbanCard) {
+		before[card.ID] = placed{col, card, assignedClient(projects, card)}
+	})
+
+	changes := KanbanChanges{Assigned: map[string][]Notification{}}
+	seen := map[string]bool{}
+	board.Cards(func(col *KanbanColumn, card *KanbanCard) {
+		seen[card.ID] = true
+		change := CardChange{Card: *card, ColumnID: col.ID, ColumnTitle: col.Title}
+		old, existed := before[card.ID]
+		switch {
+		case !existed:
+			changes.Created = append(changes.Created, change)
+		case old.col.ID != col.ID:
+			change.FromColumnID, change.FromColumnTitle = old.col.ID, old.col.Title
+			changes.Moved = append(changes.Moved, change)
+		}
+
+		client := assignedClient(projects, card)
+		if client == "" || client == owner || client == old.assignee {
+			return
+		}
+		changes.Assigned[client] = append(changes.Assigned[client], Notification{
+			Type:      NotificationAssigned,
+			Owner:     owner,
+			CardID:    card.ID,
+			ProjectID: card.ProjectID,
+			Title:     card.Title,
+			DueDate:   card.DueDate,
+		})
+	})
+	prev.Cards(func(col *KanbanColumn, card *KanbanCard) {
+		if !seen[card.ID] {
+			changes.Deleted = append(changes.Deleted, CardChange{Card: *card, ColumnID: col.ID, ColumnTitle: col.Title})
+		}
+	})
+	return changes
+}
+
+// NewCard is a card created from outside the board. Column is the ID or
+// title of the column it goes to; empty means the first column.
+type NewCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Priority    string `json:"priority"`
+	Column      string `json:"column"`
+}
+
+// AddCard appends a card to a column of the board of persona. Columns are
+// matched by ID first, then by title ignoring case.
+func AddCard(s CelerixStore, persona string, in NewCard, now time.Time) (CardChange, error) {
+	in.Title = strings.TrimSpace(in.Title)
+	in.Priority = strings.ToLower(strings.TrimSpace(in.Priority))
+	in.Column = strings.TrimSpace(in.Column)
+	problems := map[string]string{}
+	if in.Title == "" {
+		problems["title"] = "is required"
+	}
+	if in.Priority != "" && !slices.Contains(Priorities, in.Priority) {
+		problems["priority"] = "must be one of " + strings.Join(Priorities, ", ")
+	}
+	if len(problems) > 0 {
+		return CardChange{}, Validation("Invalid card").WithDetails(problems)
+	}
+
+	boardMu.Lock()
+	defer boardMu.Unlock()
+
+	board, _, err := GetKanban(s, persona)
+	if err != nil {
+		return CardChange{}, err
+	}
+	if len(board.Columns) == 0 {
+		return CardChange{}, Validation("The board has no columns to add the card to")
+	}
+	col := &board.Columns[0]
+	if in.Column != "" {
+		i := slices.IndexFunc(board.Columns, func(c KanbanColumn) bool { return c.ID == in.Column })
+		if i < 0 {
+			i = slices.IndexFunc(board.Columns, func(c KanbanColumn) bool { return strings.EqualFold(c.Title, in.Column) })
+		}
+		if i < 0 {
+			return CardChange{}, Validation("Unknown column").WithDetails(map[string]string{"column": in.Column})
+		}
+		col = &board.Columns[i]
+	}
+
+	card := KanbanCard{
+		ID:          uuid.NewString(),
+		Title:       in.Title,
+		Description: in.Description,
+		Priority:    in.Priority,
+		CreatedAt:   now.UnixMilli(),
+	}
+	col.Cards = append(col.Cards, card)
+	board.normalize()
+	if err := s.Set(persona, AppID, KanbanKey, board); err != nil {
+		return CardChange{}, err
+	}
+	return CardChange{Card: col.Cards[len(col.Cards)-1], ColumnID: col.ID, ColumnTitle: col.Title}, nil
+}
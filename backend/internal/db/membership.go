@@ -0,0 +1,203 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/celerix-dev/celerix-flow/internal/logging"
+)
+
+// Membership is a project, of any persona, that a client is linked to
+// through one of the project's users. Client IDs are credentials, so the
+// owner is only shown by handle and name, and the project's users by
+// nickname; only the member's own user keeps its client ID.
+type Membership struct {
+	OwnerID   string      `json:"-"`
+	Owner     string      `json:"owner"`
+	OwnerName string      `json:"ownerName"`
+	Project   Project     `json:"project"`
+	User      ProjectUser `json:"user"`
+}
+
+// OwnerHandle identifies the owner of a board to other clients without
+// revealing its client ID.
+func OwnerHandle(id string) string {
+	return logging.Fingerprint(id)
+}
+
+// ownerName is the name other clients see for the client id.
+func ownerName(s CelerixStore, id string) string {
+	client, err := GetClient(s, id)
+	if err != nil {
+		return "Unknown"
+	}
+	return client.Name
+}
+
+// memberView returns p as clientID may see it: without the client IDs of
+// the other users.
+func memberView(p Project, clientID string) Project {
+	users := make([]ProjectUser, len(p.Users))
+	for i, u := range p.Users {
+		if u.ClientID != clientID {
+			u.ClientID = ""
+		}
+		users[i] = u
+	}
+	p.Users = users
+	return p
+}
+
+// checkClientLinks reports project users linked to clients that do not
+// exist. Links unchanged since old are not checked again, so deleting a
+// client does not make its former projects unsaveable.
+func checkClientLinks(s CelerixStore, old, updated []Project) error {
+	linked := map[string]bool{}
+	for _, p := range old {
+		for _, u := range p.Users {
+			if u.ClientID != "" {
+				linked[p.ID+"/"+u.ID+"/"+u.ClientID] = true
+			}
+		}
+	}
+
+	problems := map[string]string{}
+	for _, p := range updated {
+		for i, u := range p.Users {
+			if u.ClientID == "" || linked[p.ID+"/"+u.ID+"/"+u.ClientID] {
+				continue
+			}
+			if _, err := GetClient(s, u.ClientID); err != nil {
+				if !errors.Is(err, ErrNotFound) {
+					return err
+				}
+				problems[fmt.Sprintf("%s.users[%d].clientId", p.ID, i)] = "is not a known client"
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return Validation("Project users are linked to unknown clients").WithDetails(problems)
+	}
+	return nil
+}
+
+// Memberships lists the projects of every persona that have a user linked
+// to clientID, ordered by owner and project name.
+func Memberships(s CelerixStore, clientID string) ([]Membership, error) {
+	memberships := []Membership{}
+	if clientID == "" {
+		return memberships, nil
+	}
+
+	dump, err := s.DumpApp(AppID)
+	if isMissing(err) {
+		return memberships, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for owner, keys := range dump {
+		val, ok := keys[ProjectsKey]
+		if !ok {
+			continue
+		}
+		data, err := DecodeProjects(val)
+		if err != nil {
+			// One persona's broken blob must not hide every other project
+			continue
+		}
+		for _, p := range data.Projects {
+			for _, u := range p.Users {
+				if u.ClientID == clientID {
+					memberships = append(memberships, Membership{
+						OwnerID:   owner,
+						Owner:     OwnerHandle(owner),
+						OwnerName: ownerName(s, owner),
+						Project:   memberView(p, clientID),
+						User:      u,
+					})
+					break
+				}
+			}
+		}
+	}
+
+	sort.Slice(memberships, func(i, j int) bool {
+		a, b := memberships[i], memberships[j]
+		if a.OwnerID != b.OwnerID {
+			return a.OwnerID < b.OwnerID
+		}
+		return strings.ToLower(a.Project.Name) < strings.ToLower(b.Project.Name)
+	})
+	return memberships, nil
+}
+
+// ProjectAccess returns a project of owner that clientID may see: owners see
+// all their projects, other clients only those they are linked to.
+func ProjectAccess(s CelerixStore, owner, projectID, clientID string) (*Project, error) {
+	project, err := GetProject(s, owner, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == clientID {
+		return project, nil
+	}
+	for _, u := range project.Users {
+		if u.ClientID != "" && u.ClientID == clientID {
+			return project, nil
+		}
+	}
+	// Outsiders learn as little about other personas' projects as possible
+	return nil, NotFound("Project not found")
+}
+
+// ProjectOwner finds the client owning the project projectID that clientID
+// asks for. handle is the OwnerHandle of the owner as listed in the
+// client's memberships; without it the client's own project is preferred,
+// then the first membership with that project ID.
+func ProjectOwner(s CelerixStore, clientID, projectID, handle string) (string, error) {
+	if handle == "" || handle == OwnerHandle(clientID) {
+		if _, err := GetProject(s, clientID, projectID); err == nil {
+			return clientID, nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	memberships, err := Memberships(s, clientID)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range memberships {
+		if m.Project.ID == projectID && (handle == "" || m.Owner == handle) {
+			return m.OwnerID, nil
+		}
+	}
+	return "", NotFound("Project not found")
+}
+
+// ProjectBoard returns the board of owner reduced to the cards of one
+// project, for clientID with access to that project. Every column is kept
+// so the board keeps its shape.
+func ProjectBoard(s CelerixStore, owner, projectID, clientID string) (KanbanBoard, error) {
+	if _, err := ProjectAccess(s, owner, projectID, clientID); err != nil {
+		return KanbanBoard{}, err
+	}
+	board, _, err := GetKanban(s, owner)
+	if err != nil {
+		return KanbanBoard{}, err
+	}
+	board.normalize()
+	for i := range board.Columns {
+		col := &board.Columns[i]
+		cards := []KanbanCard{}
+		for _, card := range col.Cards {
+			if card.ProjectID == projectID {
+				cards = append(cards, card)
+			}
+		}
+		col.Cards = cards
+	}
+	return board, nil
+}
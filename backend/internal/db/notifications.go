@@ -0,0 +1,322 @@
+package db
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/google/uuid"
+)
+
+const (
+	// NotificationsKey holds the notifications of a client, newest first.
+	NotificationsKey = "notifications"
+	// RemindedKey records the due reminders a client has had, so they are
+	// not repeated once maxNotifications drops the notification itself.
+	RemindedKey = "notifications_reminded"
+
+	NotificationDueSoon    = "card.due_soon"
+	NotificationOverdue    = "card.overdue"
+	NotificationAssigned   = "card.assigned"
+	NotificationDownloaded = "file.downloaded"
+
+	// PurposeDone marks columns of finished cards, which are never due.
+	PurposeDone = "done"
+
+	// downloadQuiet is how long further downloads of a file go unnotified
+	// after one was.
+	downloadQuiet = time.Hour
+
+	// maxNotifications bounds the stored notifications of a client; the
+	// oldest are dropped first.
+	maxNotifications = 200
+)
+
+// Notification tells a client about a card on the board of Owner, or about
+// a download of one of its shared files. Times are unix seconds.
+type Notification struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Owner     string `json:"owner"`
+	CardID    string `json:"card_id,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	Title     string `json:"title"`
+	DueDate   string `json:"due_date,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	ReadAt    int64  `json:"read_at,omitempty"`
+}
+
+// same reports whether n and o are about the same event, so it is only
+// notified once. A changed due date is a new event, every assignment is
+// one, and downloads of a file are one per downloadQuiet.
+func (n Notification) same(o Notification) bool {
+	if n.Type != o.Type || n.Owner != o.Owner {
+		return false
+	}
+	switch n.Type {
+	case NotificationAssigned:
+		return false
+	case NotificationDownloaded:
+		return n.FileID == o.FileID && n.CreatedAt-o.CreatedAt < int64(downloadQuiet/time.Second)
+	}
+	return n.CardID == o.CardID && n.DueDate == o.DueDate
+}
+
+// reminder returns the key n is recorded under in RemindedKey, if n is a
+// due reminder.
+func (n Notification) reminder() (string, bool) {
+	if n.Type != NotificationDueSoon && n.Type != NotificationOverdue {
+		return "", false
+	}
+	return n.Type + "/" + n.Owner + "/" + n.CardID + "/" + n.DueDate, true
+}
+
+// notificationsMu serializes read-modify-write cycles on NotificationsKey
+// and RemindedKey.
+var notificationsMu sync.Mutex
+
+func getNotifications(s CelerixStore, clientID string) ([]Notification, error) {
+	list, err := sdk.Get[[]Notification](s, clientID, AppID, NotificationsKey)
+	if isMissing(err) {
+		return []Notification{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// getReminded returns when each due reminder of a client was recorded.
+func getReminded(s CelerixStore, clientID string) (map[string]int64, error) {
+	reminded, err := sdk.Get[map[string]int64](s, clientID, AppID, RemindedKey)
+	if isMissing(err) || (err == nil && reminded == nil) {
+		return map[string]int64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reminded, nil
+}
+
+// ListNotifications returns the notifications of a client, newest first,
+// and how many of them are unread.
+func ListNotifications(s CelerixStore, clientID string, unreadOnly bool) ([]Notification, int, error) {
+	list, err := getNotifications(s, clientID)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := []Notification{}
+	unread := 0
+	for _, n := range list {
+		if n.ReadAt == 0 {
+			unread++
+		}
+		if !unreadOnly || n.ReadAt == 0 {
+			out = append(out, n)
+		}
+	}
+	return out, unread, nil
+}
+
+// AddNotifications stores the notifications a client has not had yet and
+// returns them with their IDs.
+func AddNotifications(s CelerixStore, clientID string, notifications []Notification, now time.Time) ([]Notification, error) {
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	list, err := getNotifications(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+	reminded, err := getReminded(s, clientID)
+	if err != nil {
+		return nil, err
+	}
+	var added []Notification
+	remindedChanged := false
+	for _, n := range notifications {
+		n.CreatedAt = now.Unix()
+		key, isReminder := n.reminder()
+		if isReminder && reminded[key] != 0 {
+			continue
+		}
+		if isReminder {
+			reminded[key] = now.Unix()
+			remindedChanged = true
+		}
+		if slices.ContainsFunc(list, n.same) || slices.ContainsFunc(added, n.same) {
+			continue
+		}
+		n.ID = uuid.NewString()
+		n.ReadAt = 0
+		added = append(added, n)
+	}
+	if remindedChanged {
+		if err := s.Set(clientID, AppID, RemindedKey, reminded); err != nil {
+			return nil, err
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	list = append(slices.Clone(added), list...)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+	if len(list) > maxNotifications {
+		list = list[:maxNotifications]
+	}
+	if err := s.Set(clientID, AppID, NotificationsKey, list); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ForgetReminders drops the recorded due reminders of a client that are not
+// among current, the reminders it is due now, so a card that is due again
+// later is reminded again.
+func ForgetReminders(s CelerixStore, clientID string, current []Notification) error {
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	reminded, err := getReminded(s, clientID)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(current))
+	for _, n := range current {
+		if key, ok := n.reminder(); ok {
+			keep[key] = true
+		}
+	}
+	forgotten := 0
+	for key := range reminded {
+		if !keep[key] {
+			delete(reminded, key)
+			forgotten++
+		}
+	}
+	if forgotten == 0 {
+		return nil
+	}
+	return s.Set(clientID, AppID, RemindedKey, reminded)
+}
+
+// MarkNotificationsRead marks the given notifications of a client read, all
+// of them if ids is empty, and returns how many were unread.
+func MarkNotificationsRead(s CelerixStore, clientID string, ids []string, now time.Time) (int, error) {
+	notificationsMu.Lock()
+	defer notificationsMu.Unlock()
+
+	list, err := getNotifications(s, clientID)
+	if err != nil {
+		return 0, err
+	}
+	marked := 0
+	for i := range list {
+		n := &list[i]
+		if n.ReadAt != 0 || (len(ids) > 0 && !slices.Contains(ids, n.ID)) {
+			continue
+		}
+		n.ReadAt = now.Unix()
+		marked++
+	}
+	if marked == 0 {
+		return 0, nil
+	}
+	return marked, s.Set(clientID, AppID, NotificationsKey, list)
+}
+
+// DueReminders finds the cards on every board that are due within soon of
+// now or overdue, and returns the notifications about them by client: the
+// board owner hears about every card, a client linked to the assignee about
+// the cards assigned to them. Cards in done columns and trashed clients are
+// left out. Plain due dates are read in now's location and due at the end of
+// that day.
+func DueReminders(s CelerixStore, now time.Time, soon time.Duration) (map[string][]Notification, error) {
+	dump, err := s.DumpApp(AppID)
+	if isMissing(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Trashed clients hear nothing, neither about their board nor as assignee
+	trashed := map[string]bool{}
+	for k := range dump[SystemPersona] {
+		if id, ok := strings.CutPrefix(k, TrashClientKeyPrefix); ok {
+			trashed[id] = true
+		}
+	}
+
+	reminders := map[string][]Notification{}
+	for owner, keys := range dump {
+		val, ok := keys[KanbanKey]
+		if !ok || owner == SystemPersona || trashed[owner] {
+			continue
+		}
+		board, err := DecodeKanban(val)
+		if err != nil {
+			continue
+		}
+		var projects []Project
+		if val, ok := keys[ProjectsKey]; ok {
+			if data, err := DecodeProjects(val); err == nil {
+				projects = data.Projects
+			}
+		}
+
+		board.Cards(func(col *KanbanColumn, card *KanbanCard) {
+			if col.Purpose == PurposeDone {
+				return
+			}
+			due, dateOnly, ok := parseDue(card.DueDate, now.Location())
+			if !ok {
+				return
+			}
+			if dateOnly {
+				due = due.AddDate(0, 0, 1)
+			}
+			kind := NotificationDueSoon
+			switch {
+			case !due.After(now):
+				kind = NotificationOverdue
+			case due.Sub(now) > soon:
+				return
+			}
+
+			n := Notification{
+				Type:      kind,
+				Owner:     owner,
+				CardID:    card.ID,
+				ProjectID: card.ProjectID,
+				Title:     card.Title,
+				DueDate:   card.DueDate,
+			}
+			reminders[owner] = append(reminders[owner], n)
+			if assignee := assignedClient(projects, card); assignee != "" && assignee != owner && !trashed[assignee] {
+				reminders[assignee] = append(reminders[assignee], n)
+			}
+		})
+	}
+	return reminders, nil
+}
+
+// assignedClient returns the client linked to the assignee of card, if any.
+func assignedClient(projects []Project, card *KanbanCard) string {
+	if card.Assignee == "" {
+		return ""
+	}
+	for i := range projects {
+		if projects[i].ID == card.ProjectID {
+			if user := projects[i].userByNickname(card.Assignee); user != nil {
+				return user.ClientID
+			}
+		}
+	}
+	return ""
+}
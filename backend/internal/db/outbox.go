@@ -0,0 +1,144 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/google/uuid"
+)
+
+const (
+	// OutboxKeyPrefix prefixes the queued emails in the system persona.
+	OutboxKeyPrefix = "outbox:"
+
+	OutboxPending = "pending"
+	OutboxFailed  = "failed"
+
+	// MaxMailAttempts is how often an email is tried before it is given up.
+	MaxMailAttempts = 8
+
+	mailRetryBase = time.Minute
+	mailRetryMax  = 6 * time.Hour
+)
+
+// OutboxEntry is a rendered email waiting to be sent. Sent emails are
+// removed; failed ones stay for inspection. Times are unix seconds.
+type OutboxEntry struct {
+	ID          string `json:"id"`
+	ClientID    string `json:"client_id"`
+	To          string `json:"to"`
+	Subject     string `json:"subject"`
+	Text        string `json:"text"`
+	HTML        string `json:"html"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	NextAttempt int64  `json:"next_attempt_at"`
+	LastError   string `json:"last_error,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// outboxMu serializes read-modify-write cycles on outbox entries.
+var outboxMu sync.Mutex
+
+// EnqueueMail queues an email to be sent right away.
+func EnqueueMail(s CelerixStore, e OutboxEntry, now time.Time) (OutboxEntry, error) {
+	e.ID = uuid.NewString()
+	e.Status = OutboxPending
+	e.Attempts = 0
+	e.NextAttempt = now.Unix()
+	e.LastError = ""
+	e.CreatedAt = now.Unix()
+	if err := s.Set(SystemPersona, AppID, OutboxKeyPrefix+e.ID, e); err != nil {
+		return OutboxEntry{}, err
+	}
+	return e, nil
+}
+
+// ListOutbox returns the queued and failed emails, oldest first.
+func ListOutbox(s CelerixStore) ([]OutboxEntry, error) {
+	keys, err := GetPersonaKeys(s, SystemPersona)
+	if err != nil {
+		return nil, err
+	}
+	entries := []OutboxEntry{}
+	for k := range keys {
+		if !strings.HasPrefix(k, OutboxKeyPrefix) {
+			continue
+		}
+		e, err := sdk.Get[OutboxEntry](s, SystemPersona, AppID, k)
+		if err == nil {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreatedAt != entries[j].CreatedAt {
+			return entries[i].CreatedAt < entries[j].CreatedAt
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// DueMail returns the pending emails whose next attempt is due at now.
+func DueMail(s CelerixStore, now time.Time) ([]OutboxEntry, error) {
+	entries, err := ListOutbox(s)
+	if err != nil {
+		return nil, err
+	}
+	due := []OutboxEntry{}
+	for _, e := range entries {
+		if e.Status == OutboxPending && e.NextAttempt <= now.Unix() {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+// MailSent removes a delivered email from the outbox.
+func MailSent(s CelerixStore, id string) error {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	err := s.Delete(SystemPersona, AppID, OutboxKeyPrefix+id)
+	if isMissing(err) {
+		return nil
+	}
+	return err
+}
+
+// MailFailed records a failed attempt. The next one is scheduled with an
+// exponential backoff; after MaxMailAttempts the email is marked failed.
+func MailFailed(s CelerixStore, id string, sendErr error, now time.Time) (OutboxEntry, error) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	e, err := sdk.Get[OutboxEntry](s, SystemPersona, AppID, OutboxKeyPrefix+id)
+	if err != nil {
+		return OutboxEntry{}, storeError(err, "Outbox entry not found")
+	}
+	e.Attempts++
+	e.LastError = sendErr.Error()
+	if e.Attempts >= MaxMailAttempts {
+		e.Status = OutboxFailed
+		e.NextAttempt = 0
+	} else {
+		e.NextAttempt = now.Add(retryDelay(mailRetryBase, mailRetryMax, e.Attempts)).Unix()
+	}
+	if err := s.Set(SystemPersona, AppID, OutboxKeyPrefix+e.ID, e); err != nil {
+		return OutboxEntry{}, err
+	}
+	return e, nil
+}
+
+// retryDelay is the wait after the given number of failed attempts: base,
+// doubled for every further attempt up to limit.
+func retryDelay(base, limit time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
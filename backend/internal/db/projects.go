@@ -0,0 +1,382 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ProjectsKey holds the projects of a persona as one ProjectsData value,
+	// the shape the frontend has always stored through /store/PROJECTS.
+	ProjectsKey = "PROJECTS"
+
+	ProjectsVersion    = "1.0.0"
+	ProjectVersion     = "1.0.0"
+	ProjectUserVersion = "1.0.0"
+
+	DefaultProjectIcon  = "ti-package"
+	DefaultProjectColor = "#3b82f6"
+
+	maxProjectName = 200
+	maxProjectTag  = 50
+)
+
+// ProjectUser is a member of a project. Cards name their assignee by
+// nickname. ClientID optionally links the user to a client account, which
+// makes the project and the cards assigned to the user visible to that
+// client.
+type ProjectUser struct {
+	Version   string `json:"version"`
+	ID        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Nickname  string `json:"nickname"`
+	ClientID  string `json:"clientId,omitempty"`
+}
+
+type Project struct {
+	Version     string        `json:"version"`
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Tag         string        `json:"tag"`
+	Icon        string        `json:"icon"`
+	Color       string        `json:"color"`
+	Description string        `json:"description"`
+	CreatedAt   int64         `json:"createdAt"`
+	Users       []ProjectUser `json:"users"`
+}
+
+// ProjectsData is the stored value under ProjectsKey.
+type ProjectsData struct {
+	Version  string    `json:"version"`
+	Projects []Project `json:"projects"`
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// boardMu serializes read-modify-write cycles on ProjectsKey and KanbanKey,
+// which reference each other.
+var boardMu sync.Mutex
+
+// normalize fills in defaults and generated IDs and trims names.
+func (p *Project) normalize() {
+	p.Version = ProjectVersion
+	p.Name = strings.TrimSpace(p.Name)
+	p.Tag = strings.TrimSpace(p.Tag)
+	if p.Icon == "" {
+		p.Icon = DefaultProjectIcon
+	}
+	if p.Color == "" {
+		p.Color = DefaultProjectColor
+	}
+	if p.Users == nil {
+		p.Users = []ProjectUser{}
+	}
+	for i := range p.Users {
+		u := &p.Users[i]
+		u.Version = ProjectUserVersion
+		if u.ID == "" {
+			u.ID = uuid.NewString()
+		}
+		u.FirstName = strings.TrimSpace(u.FirstName)
+		u.LastName = strings.TrimSpace(u.LastName)
+		u.Nickname = strings.TrimSpace(u.Nickname)
+		u.ClientID = strings.TrimSpace(u.ClientID)
+	}
+}
+
+// Validate reports every invalid field of a normalized project; the details
+// map field names to problems.
+func (p *Project) Validate() error {
+	problems := map[string]string{}
+	switch {
+	case p.Name == "":
+		problems["name"] = "is required"
+	case len(p.Name) > maxProjectName:
+		problems["name"] = fmt.Sprintf("must be at most %d characters", maxProjectName)
+	}
+	switch {
+	case p.Tag == "":
+		problems["tag"] = "is required"
+	case len(p.Tag) > maxProjectTag:
+		problems["tag"] = fmt.Sprintf("must be at most %d characters", maxProjectTag)
+	case strings.IndexFunc(p.Tag, unicode.IsSpace) >= 0:
+		problems["tag"] = "must not contain spaces"
+	}
+	if !colorPattern.MatchString(p.Color) {
+		problems["color"] = "must be a hex color like #3b82f6"
+	}
+
+	ids := map[string]bool{}
+	nicknames := map[string]bool{}
+	clients := map[string]bool{}
+	for i, u := range p.Users {
+		field := fmt.Sprintf("users[%d]", i)
+		if ids[u.ID] {
+			problems[field+".id"] = "is used twice"
+		}
+		ids[u.ID] = true
+		nick := strings.ToLower(u.Nickname)
+		if nick != "" && nicknames[nick] {
+			problems[field+".nickname"] = "is used twice in this project"
+		}
+		nicknames[nick] = true
+		if u.ClientID != "" && clients[u.ClientID] {
+			problems[field+".clientId"] = "is linked to another user of this project"
+		}
+		clients[u.ClientID] = true
+	}
+
+	if len(problems) > 0 {
+		return Validation("Invalid project").WithDetails(problems)
+	}
+	return nil
+}
+
+// validateProjects validates every project and the uniqueness of IDs and
+// tags across them. Tags are compared case-insensitively.
+func validateProjects(projects []Project) error {
+	ids := map[string]bool{}
+	tags := map[string]string{}
+	for i := range projects {
+		p := &projects[i]
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if ids[p.ID] {
+			return Conflict("Project ID %s is used twice", p.ID)
+		}
+		ids[p.ID] = true
+		tag := strings.ToLower(p.Tag)
+		if other, ok := tags[tag]; ok {
+			return Conflict("Tag %q is already used by project %s", p.Tag, other).
+				WithDetails(map[string]string{"tag": p.Tag, "project_id": other})
+		}
+		tags[tag] = p.ID
+	}
+	return nil
+}
+
+// DecodeProjects reads a stored or submitted projects value. Besides the
+// versioned object it accepts the bare array older frontends stored.
+func DecodeProjects(val any) (ProjectsData, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return ProjectsData{}, err
+	}
+
+	var data ProjectsData
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		err = json.Unmarshal(raw, &data.Projects)
+	} else {
+		err = json.Unmarshal(raw, &data)
+	}
+	if err != nil {
+		return ProjectsData{}, Validation("Projects have an unexpected format").Wrap(err)
+	}
+	return data, nil
+}
+
+// GetProjects returns the projects of a persona, none if nothing is stored.
+func GetProjects(s CelerixStore, persona string) (ProjectsData, error) {
+	val, err := s.Get(persona, AppID, ProjectsKey)
+	if isMissing(err) {
+		return ProjectsData{Version: ProjectsVersion, Projects: []Project{}}, nil
+	}
+	if err != nil {
+		return ProjectsData{}, err
+	}
+	data, err := DecodeProjects(val)
+	if err != nil {
+		return ProjectsData{}, err
+	}
+	if data.Projects == nil {
+		data.Projects = []Project{}
+	}
+	return data, nil
+}
+
+// SaveProjects normalizes, validates and stores the complete project list of
+// a persona. Cards referencing removed projects or users are handled as opts
+// say, in the same transaction.
+func SaveProjects(s CelerixStore, persona string, data ProjectsData, opts CascadeOptions) error {
+	return updateProjects(s, persona, opts, func(stored *ProjectsData) error {
+		*stored = data
+		return nil
+	})
+}
+
+// prepareProjects normalizes data and validates it as the new project list
+// replacing old.
+func prepareProjects(s CelerixStore, data *ProjectsData, old []Project) error {
+	data.Version = ProjectsVersion
+	if data.Projects == nil {
+		data.Projects = []Project{}
+	}
+	for i := range data.Projects {
+		data.Projects[i].normalize()
+		if data.Projects[i].ID == "" {
+			data.Projects[i].ID = uuid.NewString()
+		}
+	}
+	if err := validateProjects(data.Projects); err != nil {
+		return err
+	}
+	return checkClientLinks(s, old, data.Projects)
+}
+
+// updateProjects applies fn to the stored projects of a persona, validates
+// the result and stores it together with the kanban cards it affects.
+func updateProjects(s CelerixStore, persona string, opts CascadeOptions, fn func(*ProjectsData) error) error {
+	boardMu.Lock()
+	defer boardMu.Unlock()
+
+	data, err := GetProjects(s, persona)
+	if err != nil {
+		return err
+	}
+	old := data.Projects
+	data.Projects = make([]Project, len(old))
+	for i, p := range old {
+		p.Users = append([]ProjectUser(nil), p.Users...)
+		data.Projects[i] = p
+	}
+	if err := fn(&data); err != nil {
+		return err
+	}
+
+	if err := prepareProjects(s, &data, old); err != nil {
+		return err
+	}
+
+	board, found, err := GetKanban(s, persona)
+	if err != nil {
+		return err
+	}
+	boardChanged := false
+	if found {
+		if boardChanged, err = reconcileBoard(&board, old, data.Projects, opts); err != nil {
+			return err
+		}
+	}
+
+	tx := NewTx(s)
+	err = tx.Set(persona, ProjectsKey, data)
+	if err == nil && boardChanged {
+		err = tx.Set(persona, KanbanKey, board)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func GetProject(s CelerixStore, persona, id string) (*Project, error) {
+	data, err := GetProjects(s, persona)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range data.Projects {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, NotFound("Project not found")
+}
+
+// CreateProject stores a new project. The ID is generated unless given and
+// CreatedAt is set to now (in milliseconds, like the frontend) unless given.
+func CreateProject(s CelerixStore, persona string, p Project) (*Project, error) {
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	if p.CreatedAt == 0 {
+		p.CreatedAt = time.Now().UnixMilli()
+	}
+	err := updateProjects(s, persona, CascadeOptions{Mode: CascadeBlock}, func(data *ProjectsData) error {
+		for _, existing := range data.Projects {
+			if existing.ID == p.ID {
+				return Conflict("Project %s already exists", p.ID)
+			}
+		}
+		data.Projects = append(data.Projects, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetProject(s, persona, p.ID)
+}
+
+// UpdateProject replaces a project; its ID and creation time are kept. Cards
+// follow renamed nicknames; users removed from the project are handled as
+// opts say.
+func UpdateProject(s CelerixStore, persona, id string, p Project, opts CascadeOptions) (*Project, error) {
+	err := updateProjects(s, persona, opts, func(data *ProjectsData) error {
+		for i, existing := range data.Projects {
+			if existing.ID == id {
+				p.ID = id
+				p.CreatedAt = existing.CreatedAt
+				data.Projects[i] = p
+				return nil
+			}
+		}
+		return NotFound("Project not found")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetProject(s, persona, id)
+}
+
+// DeleteProject removes a project; cards referencing it are handled as opts
+// say. A reassign target must be another project.
+func DeleteProject(s CelerixStore, persona, id string, opts CascadeOptions) error {
+	if opts.Mode == CascadeReassign && opts.Target == id {
+		return Validation("Cannot reassign cards to the deleted project")
+	}
+	return updateProjects(s, persona, opts, func(data *ProjectsData) error {
+		for i, existing := range data.Projects {
+			if existing.ID == id {
+				data.Projects = append(data.Projects[:i:i], data.Projects[i+1:]...)
+				return nil
+			}
+		}
+		return NotFound("Project not found")
+	})
+}
+
+// DeleteProjectUser removes a user from a project; cards assigned to them
+// are handled as opts say. A reassign target must be another user of the
+// project.
+func DeleteProjectUser(s CelerixStore, persona, projectID, userID string, opts CascadeOptions) error {
+	if opts.Mode == CascadeReassign && opts.Target == userID {
+		return Validation("Cannot reassign cards to the deleted user")
+	}
+	return updateProjects(s, persona, opts, func(data *ProjectsData) error {
+		for i := range data.Projects {
+			p := &data.Projects[i]
+			if p.ID != projectID {
+				continue
+			}
+			for j, u := range p.Users {
+				if u.ID == userID {
+					p.Users = append(p.Users[:j], p.Users[j+1:]...)
+					return nil
+				}
+			}
+			return NotFound("Project user not found")
+		}
+		return NotFound("Project not found")
+	})
+}
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cascade decides what happens to cards that reference a project or project
+// user being removed.
+type Cascade string
+
+const (
+	// CascadeBlock refuses the removal while cards reference it.
+	CascadeBlock Cascade = "block"
+	// CascadeClear removes the reference from the cards.
+	CascadeClear Cascade = "clear"
+	// CascadeReassign points the cards at CascadeOptions.Target instead.
+	CascadeReassign Cascade = "reassign"
+)
+
+// CascadeOptions apply to every project and project user removed by one
+// change. Target is a project ID when projects are removed and a user ID of
+// the same project when users are removed.
+type CascadeOptions struct {
+	Mode   Cascade
+	Target string
+}
+
+// ParseCascade reads a cascade mode; the empty string means CascadeBlock.
+func ParseCascade(mode, target string) (CascadeOptions, error) {
+	opts := CascadeOptions{Mode: Cascade(mode), Target: target}
+	switch opts.Mode {
+	case "":
+		opts.Mode = CascadeBlock
+	case CascadeBlock, CascadeClear:
+	case CascadeReassign:
+		if target == "" {
+			return opts, Validation("cascade=reassign requires a target")
+		}
+	default:
+		return opts, Validation("cascade must be block, clear or reassign, got %q", mode)
+	}
+	return opts, nil
+}
+
+// DanglingReference is a card field pointing at something that does not
+// exist.
+type DanglingReference struct {
+	CardID string `json:"card_id"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+}
+
+// danglingReferences lists the references of board that do not resolve
+// against projects, leaving out those unchanged since prev.
+func danglingReferences(board KanbanBoard, projects []Project, prev KanbanBoard) []DanglingReference {
+	byID := map[string]*Project{}
+	for i := range projects {
+		byID[projects[i].ID] = &projects[i]
+	}
+	before := map[string]KanbanCard{}
+	prev.Cards(func(_ *KanbanColumn, card *KanbanCard) { before[card.ID] = *card })
+
+	var dangling []DanglingReference
+	board.Cards(func(_ *KanbanColumn, card *KanbanCard) {
+		old, existed := before[card.ID]
+		unchanged := existed && old.ProjectID == card.ProjectID && old.Assignee == card.Assignee
+
+		project := byID[card.ProjectID]
+		if card.ProjectID != "" && project == nil && !unchanged {
+			dangling = append(dangling, DanglingReference{CardID: card.ID, Field: "projectId", Value: card.ProjectID})
+		}
+		if card.Assignee != "" && !unchanged {
+			if project == nil || project.userByNickname(card.Assignee) == nil {
+				dangling = append(dangling, DanglingReference{CardID: card.ID, Field: "assignee", Value: card.Assignee})
+			}
+		}
+	})
+	return dangling
+}
+
+func (p *Project) userByNickname(nickname string) *ProjectUser {
+	for i := range p.Users {
+		if strings.EqualFold(p.Users[i].Nickname, nickname) {
+			return &p.Users[i]
+		}
+	}
+	return nil
+}
+
+func (p *Project) userByID(id string) *ProjectUser {
+	for i := range p.Users {
+		if p.Users[i].ID == id {
+			return &p.Users[i]
+		}
+	}
+	return nil
+}
+
+// reconcileBoard updates the cards of board for the change of a persona's
+// projects from old to updated: renamed nicknames are followed, removed
+// projects and users are handled as opts say. It reports whether any card
+// changed. With CascadeBlock a removal that cards depend on is a conflict
+// whose details list the cards.
+func reconcileBoard(board *KanbanBoard, old, updated []Project, opts CascadeOptions) (bool, error) {
+	newByID := map[string]*Project{}
+	for i := range updated {
+		newByID[updated[i].ID] = &updated[i]
+	}
+
+	var blocked []string
+	var invalid error
+	changed := false
+	board.Cards(func(_ *KanbanColumn, card *KanbanCard) {
+		if card.ProjectID == "" {
+			return
+		}
+		var before *Project
+		for i := range old {
+			if old[i].ID == card.ProjectID {
+				before = &old[i]
+			}
+		}
+		if before == nil {
+			// The reference was already broken, not ours to fix
+			return
+		}
+		after := newByID[card.ProjectID]
+
+		if after == nil {
+			switch opts.Mode {
+			case CascadeBlock:
+				blocked = append(blocked, card.ID)
+			case CascadeClear:
+				card.ProjectID, card.Assignee = "", ""
+				changed = true
+			case CascadeReassign:
+				target := newByID[opts.Target]
+				if target == nil {
+					invalid = Validation("Target project %s does not exist", opts.Target)
+					return
+				}
+				card.ProjectID = target.ID
+				if card.Assignee != "" && target.userByNickname(card.Assignee) == nil {
+					card.Assignee = ""
+				}
+				changed = true
+			}
+			return
+		}
+
+		if card.Assignee == "" {
+			return
+		}
+		user := before.userByNickname(card.Assignee)
+		if user == nil {
+			return
+		}
+		if now := after.userByID(user.ID); now != nil {
+			if now.Nickname != card.Assignee {
+				card.Assignee = now.Nickname
+				changed = true
+			}
+			return
+		}
+
+		switch opts.Mode {
+		case CascadeBlock:
+			blocked = append(blocked, card.ID)
+		case CascadeClear:
+			card.Assignee = ""
+			changed = true
+		case CascadeReassign:
+			target := after.userByID(opts.Target)
+			if target == nil {
+				invalid = Validation("Target user %s is not a member of project %s", opts.Target, after.ID)
+				return
+			}
+			card.Assignee = target.Nickname
+			changed = true
+		}
+	})
+
+	if invalid != nil {
+		return false, invalid
+	}
+	if len(blocked) > 0 {
+		return false, Conflict("%s still referenced by %d card(s); choose cascade=clear or cascade=reassign",
+			removalSubject(old, updated), len(blocked)).WithDetails(map[string][]string{"cards": blocked})
+	}
+	return changed, nil
+}
+
+func removalSubject(old, updated []Project) string {
+	ids := map[string]bool{}
+	for _, p := range updated {
+		ids[p.ID] = true
+	}
+	for _, p := range old {
+		if !ids[p.ID] {
+			return fmt.Sprintf("Project %q is", p.Name)
+		}
+	}
+	return "Removed project users are"
+}
+
+// CheckImportedBoard normalizes and validates the projects and board a
+// persona import is about to store, as SaveProjects and SaveKanban would.
+// Either is nil if the archive has none; without replace the stored one is
+// kept then, and a kept board must still resolve against imported projects.
+func CheckImportedBoard(s CelerixStore, persona string, projects *ProjectsData, board *KanbanBoard, replace bool) error {
+	stored, err := GetProjects(s, persona)
+	if err != nil {
+		return err
+	}
+	// A broken stored board is replaced or left as is, never checked
+	storedBoard, found, _ := GetKanban(s, persona)
+
+	final := stored.Projects
+	if projects != nil {
+		if err := prepareProjects(s, projects, stored.Projects); err != nil {
+			return err
+		}
+		final = projects.Projects
+	} else if replace {
+		final = nil
+	}
+
+	var dangling []DanglingReference
+	switch {
+	case board != nil:
+		dangling = danglingReferences(*board, final, KanbanBoard{})
+		board.normalize()
+	case found && !replace && projects != nil:
+		// References the stored board had broken before are not the import's
+		broken := map[DanglingReference]bool{}
+		for _, d := range danglingReferences(storedBoard, stored.Projects, KanbanBoard{}) {
+			broken[d] = true
+		}
+		for _, d := range danglingReferences(storedBoard, final, KanbanBoard{}) {
+			if !broken[d] {
+				dangling = append(dangling, d)
+			}
+		}
+	}
+	if len(dangling) > 0 {
+		return Validation("Cards reference unknown projects or assignees").WithDetails(dangling)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/google/uuid"
+)
+
+// APITokenKeyPrefix prefixes API tokens in the system persona.
+const APITokenKeyPrefix = "api_token:"
+
+// apiTokenPrefix starts every API token: "flow_<id>_<secret>". The ID lets
+// a request's token be looked up without scanning every token.
+const apiTokenPrefix = "flow_"
+
+// Scopes of API tokens.
+const (
+	ScopeReadOnly  = "read-only"
+	ScopeReadWrite = "read-write"
+	ScopeFiles     = "files-only"
+)
+
+var TokenScopes = []string{ScopeReadOnly, ScopeReadWrite, ScopeFiles}
+
+// tokenTouchInterval limits how often the last use of a token is written.
+const tokenTouchInterval = time.Minute
+
+// APIToken lets scripts act as Owner within Scope. Only a hash of the token
+// is stored. Times are unix seconds.
+type APIToken struct {
+	ID         string `json:"id"`
+	Owner      string `json:"owner"`
+	Name       string `json:"name"`
+	Scope      string `json:"scope"`
+	TokenHash  string `json:"token_hash"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at,omitempty"`
+}
+
+// CreateAPIToken stores a new API token of owner and returns it with the
+// token to hand out, built from secret.
+func CreateAPIToken(s CelerixStore, owner, name, scope, secret string, now time.Time) (APIToken, string, error) {
+	t := APIToken{
+		ID:        uuid.NewString(),
+		Owner:     owner,
+		Name:      strings.TrimSpace(name),
+		Scope:     scope,
+		CreatedAt: now.Unix(),
+	}
+	problems := map[string]string{}
+	if t.Name == "" {
+		problems["name"] = "is required"
+	}
+	if !slices.Contains(TokenScopes, t.Scope) {
+		problems["scope"] = "must be one of " + strings.Join(TokenScopes, ", ")
+	}
+	if len(problems) > 0 {
+		return APIToken{}, "", Validation("Invalid API token").WithDetails(problems)
+	}
+
+	token := apiTokenPrefix + t.ID + "_" + secret
+	t.TokenHash = hashToken(token)
+	if err := s.Set(SystemPersona, AppID, APITokenKeyPrefix+t.ID, t); err != nil {
+		return APIToken{}, "", err
+	}
+	return t, token, nil
+}
+
+func GetAPIToken(s CelerixStore, id string) (*APIToken, error) {
+	t, err := sdk.Get[APIToken](s, SystemPersona, AppID, APITokenKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "API token not found")
+	}
+	return &t, nil
+}
+
+// ListAPITokens returns the API tokens of a client, oldest first.
+func ListAPITokens(s CelerixStore, owner string) ([]APIToken, error) {
+	keys, err := GetPersonaKeys(s, SystemPersona)
+	if err != nil {
+		return nil, err
+	}
+	tokens := []APIToken{}
+	for k := range keys {
+		if !strings.HasPrefix(k, APITokenKeyPrefix) {
+			continue
+		}
+		t, err := sdk.Get[APIToken](s, SystemPersona, AppID, k)
+		if err == nil && t.Owner == owner {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].CreatedAt != tokens[j].CreatedAt {
+			return tokens[i].CreatedAt < tokens[j].CreatedAt
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// apiTokenMu serializes recording a token's use with revoking it, so a
+// request racing the revocation cannot write the token back.
+var apiTokenMu sync.Mutex
+
+func DeleteAPIToken(s CelerixStore, id string) error {
+	apiTokenMu.Lock()
+	defer apiTokenMu.Unlock()
+	err := s.Delete(SystemPersona, AppID, APITokenKeyPrefix+id)
+	return storeError(err, "API token not found")
+}
+
+// AuthenticateAPIToken returns the API token token belongs to and records
+// its use, at most once per tokenTouchInterval. Malformed, unknown and
+// revoked tokens, and tokens of clients that are not live, all give the same
+// NotFound.
+func AuthenticateAPIToken(s CelerixStore, token string, now time.Time) (*APIToken, error) {
+	invalid := NotFound("Invalid API token")
+	id, _, ok := strings.Cut(strings.TrimPrefix(token, apiTokenPrefix), "_")
+	if !ok || !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, invalid
+	}
+	apiTokenMu.Lock()
+	defer apiTokenMu.Unlock()
+	t, err := GetAPIToken(s, id)
+	if isMissing(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(t.TokenHash), []byte(hashToken(token))) != 1 {
+		return nil, invalid
+	}
+	// Tokens of a trashed client stop working until the client is restored
+	if _, err := GetClient(s, t.Owner); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	if now.Unix()-t.LastUsedAt >= int64(tokenTouchInterval/time.Second) {
+		t.LastUsedAt = now.Unix()
+		if err := s.Set(SystemPersona, AppID, APITokenKeyPrefix+t.ID, t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// Trashed records live next to the live ones under a "trash:" prefix so that
+// every existing lookup (which matches on "file:" / "client:") ignores them
+// without extra filtering. Blobs stay on disk until the record is purged.
+
+func filePersona(ownerID string) string {
+	if ownerID == "" {
+		return SystemPersona
+	}
+	return ownerID
+}
+
+func TrashFileRecord(s CelerixStore, id string, deletedAt int64) error {
+	record, err := GetFileRecord(s, id)
+	if err != nil {
+		return err
+	}
+	persona := filePersona(record.OwnerID)
+
+	record.DeletedAt = deletedAt
+	record.OwnerName = ""
+	if err := s.Set(persona, AppID, TrashFileKeyPrefix+id, record); err != nil {
+		return err
+	}
+	return s.Delete(persona, AppID, FileKeyPrefix+id)
+}
+
+func RestoreFileRecord(s CelerixStore, id string) error {
+	record, err := GetTrashedFileRecord(s, id)
+	if err != nil {
+		return err
+	}
+	persona := filePersona(record.OwnerID)
+
+	record.DeletedAt = 0
+	record.OwnerName = ""
+	if err := s.Set(persona, AppID, FileKeyPrefix+id, record); err != nil {
+		return err
+	}
+	return s.Delete(persona, AppID, TrashFileKeyPrefix+id)
+}
+
+func GetTrashedFileRecord(s CelerixStore, id string) (*FileRecord, error) {
+	_, personaID, err := s.GetGlobal(AppID, TrashFileKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "File not found in trash")
+	}
+
+	record, err := sdk.Get[FileRecord](s, personaID, AppID, TrashFileKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "File not found in trash")
+	}
+	return &record, nil
+}
+
+// ListTrashedFiles returns trashed files, newest deletion first. An empty
+// ownerID returns the trash of every persona.
+func ListTrashedFiles(s CelerixStore, ownerID string) ([]FileRecord, error) {
+	allData, err := s.DumpApp(AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []FileRecord
+	for personaID, appStore := range allData {
+		for k := range appStore {
+			if !strings.HasPrefix(k, TrashFileKeyPrefix) {
+				continue
+			}
+			r, err := sdk.Get[FileRecord](s, personaID, AppID, k)
+			if err != nil {
+				continue
+			}
+			if ownerID == "" || r.OwnerID == ownerID {
+				records = append(records, r)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DeletedAt > records[j].DeletedAt
+	})
+
+	return records, nil
+}
+
+// PurgeFileRecord permanently removes a trashed file record. The caller is
+// responsible for removing the blob.
+func PurgeFileRecord(s CelerixStore, id string) error {
+	record, err := GetTrashedFileRecord(s, id)
+	if err != nil {
+		return err
+	}
+	return s.Delete(filePersona(record.OwnerID), AppID, TrashFileKeyPrefix+id)
+}
+
+func TrashClient(s CelerixStore, id string, deletedAt int64) error {
+	client, err := GetClient(s, id)
+	if err != nil {
+		return err
+	}
+	client.DeletedAt = deletedAt
+	if err := s.Set(SystemPersona, AppID, TrashClientKeyPrefix+id, client); err != nil {
+		return err
+	}
+	return s.Delete(SystemPersona, AppID, ClientKeyPrefix+id)
+}
+
+func GetTrashedClient(s CelerixStore, id string) (*ClientRecord, error) {
+	client, err := sdk.Get[ClientRecord](s, SystemPersona, AppID, TrashClientKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "Client not found in trash")
+	}
+	return &client, nil
+}
+
+func ListTrashedClients(s CelerixStore) ([]ClientRecord, error) {
+	appStore, err := s.GetAppStore(SystemPersona, AppID)
+	if err != nil {
+		if isMissing(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var clients []ClientRecord
+	for k := range appStore {
+		if strings.HasPrefix(k, TrashClientKeyPrefix) {
+			c, err := sdk.Get[ClientRecord](s, SystemPersona, AppID, k)
+			if err == nil {
+				clients = append(clients, c)
+			}
+		}
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].DeletedAt > clients[j].DeletedAt
+	})
+
+	return clients, nil
+}
+
+func PurgeClient(s CelerixStore, id string) error {
+	return s.Delete(SystemPersona, AppID, TrashClientKeyPrefix+id)
+}
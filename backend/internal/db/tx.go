@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+)
+
+// Tx applies a sequence of store writes within the flow app and remembers how
+// to undo each of them. The store has no native transactions, so callers run
+// their writes through a Tx and call Rollback if any later step fails.
+type Tx struct {
+	s    CelerixStore
+	undo []func() error
+}
+
+func NewTx(s CelerixStore) *Tx {
+	return &Tx{s: s}
+}
+
+// Store exposes the underlying store for reads.
+func (t *Tx) Store() CelerixStore {
+	return t.s
+}
+
+// Set writes val and remembers the previous value. A failure to read the
+// previous value is returned rather than taken for a missing key, which a
+// rollback would then delete.
+func (t *Tx) Set(persona, key string, val any) error {
+	prev, err := GetValue(t.s, persona, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	existed := err == nil
+
+	if err := t.s.Set(persona, AppID, key, val); err != nil {
+		return err
+	}
+
+	t.undo = append(t.undo, func() error {
+		if existed {
+			return t.s.Set(persona, AppID, key, prev)
+		}
+		return t.s.Delete(persona, AppID, key)
+	})
+	return nil
+}
+
+func (t *Tx) Delete(persona, key string) error {
+	prev, err := GetValue(t.s, persona, key)
+	if errors.Is(err, ErrNotFound) {
+		// Nothing to delete, nothing to undo
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := t.s.Delete(persona, AppID, key); err != nil {
+		return err
+	}
+
+	t.undo = append(t.undo, func() error {
+		return t.s.Set(persona, AppID, key, prev)
+	})
+	return nil
+}
+
+func (t *Tx) Move(srcPersona, dstPersona, key string) error {
+	if srcPersona == dstPersona {
+		return nil
+	}
+	if err := t.s.Move(srcPersona, dstPersona, AppID, key); err != nil {
+		return err
+	}
+
+	t.undo = append(t.undo, func() error {
+		return t.s.Move(dstPersona, srcPersona, AppID, key)
+	})
+	return nil
+}
+
+// OnRollback registers a compensating action for work done outside the store,
+// such as staging a blob for deletion.
+func (t *Tx) OnRollback(fn func() error) {
+	t.undo = append(t.undo, fn)
+}
+
+// Rollback undoes every recorded step in reverse order. It keeps going after
+// a failed step and returns all errors joined.
+func (t *Tx) Rollback() error {
+	var errs []error
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.undo = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/google/uuid"
+)
+
+const (
+	// WebhookKeyPrefix prefixes webhook subscriptions in the system persona.
+	WebhookKeyPrefix = "webhook:"
+	// DeliveryKeyPrefix prefixes webhook deliveries in the system persona.
+	DeliveryKeyPrefix = "webhook_delivery:"
+
+	EventCardCreated   = "card.created"
+	EventCardMoved     = "card.moved"
+	EventCardDeleted   = "card.deleted"
+	EventFileUploaded  = "file.uploaded"
+	EventClientDeleted = "client.deleted"
+
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+
+	// MaxWebhookAttempts is how often a delivery is tried before it is
+	// given up.
+	MaxWebhookAttempts = 6
+
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+
+	// maxDeliveryLog bounds the finished deliveries kept per webhook; the
+	// oldest are dropped first.
+	maxDeliveryLog = 50
+)
+
+// WebhookEvents lists the events webhooks can subscribe to.
+var WebhookEvents = []string{EventCardCreated, EventCardMoved, EventCardDeleted, EventFileUploaded, EventClientDeleted}
+
+// Webhook subscribes a URL to events. A webhook of a client only receives
+// events about the client's own data; one without an owner, which only
+// admins manage, receives the events of every client.
+type Webhook struct {
+	ID        string   `json:"id"`
+	Owner     string   `json:"owner,omitempty"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`
+	Events    []string `json:"events"`
+	Disabled  bool     `json:"disabled,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+// WebhookEvent is the payload posted to subscribers. OwnerID is the client
+// whose data the event is about; subscribers only get its OwnerHandle, as
+// client IDs are credentials.
+type WebhookEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	OwnerID   string `json:"-"`
+	Owner     string `json:"owner"`
+	CreatedAt int64  `json:"created_at"`
+	Data      any    `json:"data"`
+}
+
+// WebhookDelivery is one event on its way to one webhook, and after that
+// its entry in the delivery log. Times are unix seconds.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttempt    int64           `json:"next_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      int64           `json:"created_at"`
+	DeliveredAt    int64           `json:"delivered_at,omitempty"`
+}
+
+// webhooksMu serializes read-modify-write cycles on webhooks and
+// deliveries.
+var webhooksMu sync.Mutex
+
+func (w *Webhook) normalize() {
+	w.URL = strings.TrimSpace(w.URL)
+	events := slices.Clone(w.Events)
+	slices.Sort(events)
+	w.Events = slices.Compact(events)
+}
+
+func (w *Webhook) validate() error {
+	problems := map[string]string{}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems["url"] = "must be an absolute http or https URL"
+	}
+	if len(w.Events) == 0 {
+		problems["events"] = "must name at least one event"
+	}
+	for _, e := range w.Events {
+		if !slices.Contains(WebhookEvents, e) {
+			problems["events"] = "unknown event " + e + ", must be one of " + strings.Join(WebhookEvents, ", ")
+			break
+		}
+	}
+	if len(problems) > 0 {
+		return Validation("Invalid webhook").WithDetails(problems)
+	}
+	return nil
+}
+
+// wants reports whether w receives e.
+func (w *Webhook) wants(e WebhookEvent) bool {
+	return !w.Disabled && (w.Owner == "" || w.Owner == e.OwnerID) && slices.Contains(w.Events, e.Type)
+}
+
+// CreateWebhook stores a new webhook. It gets a random secret unless one is
+// given.
+func CreateWebhook(s CelerixStore, w Webhook, secret func() string, now time.Time) (Webhook, error) {
+	w.normalize()
+	if err := w.validate(); err != nil {
+		return Webhook{}, err
+	}
+	w.ID = uuid.NewString()
+	if w.Secret == "" {
+		w.Secret = secret()
+	}
+	w.CreatedAt = now.Unix()
+	if err := s.Set(SystemPersona, AppID, WebhookKeyPrefix+w.ID, w); err != nil {
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+func GetWebhook(s CelerixStore, id string) (*Webhook, error) {
+	w, err := sdk.Get[Webhook](s, SystemPersona, AppID, WebhookKeyPrefix+id)
+	if err != nil {
+		return nil, storeError(err, "Webhook not found")
+	}
+	return &w, nil
+}
+
+// UpdateWebhook replaces the URL, events and disabled flag of a webhook,
+// and its secret if one is given.
+func UpdateWebhook(s CelerixStore, w Webhook) (Webhook, error) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	old, err := GetWebhook(s, w.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w.normalize()
+	if err := w.validate(); err != nil {
+		return Webhook{}, err
+	}
+	w.Owner = old.Owner
+	w.CreatedAt = old.CreatedAt
+	if w.Secret == "" {
+		w.Secret = old.Secret
+	}
+	if err := s.Set(SystemPersona, AppID, WebhookKeyPrefix+w.ID, w); err != nil {
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+// DeleteWebhook removes a webhook with its deliveries.
+func DeleteWebhook(s CelerixStore, id string) error {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	if _, err := GetWebhook(s, id); err != nil {
+		return err
+	}
+	deliveries, err := listDeliveries(s, func(d *WebhookDelivery) bool { return d.WebhookID == id })
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		if err := s.Delete(SystemPersona, AppID, DeliveryKeyPrefix+d.ID); err != nil && !isMissing(err) {
+			return err
+		}
+	}
+	return s.Delete(SystemPersona, AppID, WebhookKeyPrefix+id)
+}
+
+// ListWebhooks returns the webhooks of a client, or all of them if all is
+// set, oldest first.
+func ListWebhooks(s CelerixStore, owner string, all bool) ([]Webhook, error) {
+	keys, err := GetPersonaKeys(s, SystemPersona)
+	if err != nil {
+		return nil, err
+	}
+	hooks := []Webhook{}
+	for k := range keys {
+		if !strings.HasPrefix(k, WebhookKeyPrefix) {
+			continue
+		}
+		w, err := sdk.Get[Webhook](s, SystemPersona, AppID, k)
+		if err == nil && (all || w.Owner == owner) {
+			hooks = append(hooks, w)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		if hooks[i].CreatedAt != hooks[j].CreatedAt {
+			return hooks[i].CreatedAt < hooks[j].CreatedAt
+		}
+		return hooks[i].ID < hooks[j].ID
+	})
+	return hooks, nil
+}
+
+// QueueWebhookEvent queues a delivery of e to every active webhook that
+// subscribes to it, and returns how many were queued.
+func QueueWebhookEvent(s CelerixStore, e WebhookEvent, now time.Time) (int, error) {
+	hooks, err := ListWebhooks(s, "", true)
+	if err != nil {
+		return 0, err
+	}
+	e.ID = uuid.NewString()
+	e.Owner = OwnerHandle(e.OwnerID)
+	e.CreatedAt = now.Unix()
+	var payload []byte
+	queued := 0
+	for i := range hooks {
+		if !hooks[i].wants(e) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				return queued, err
+			}
+		}
+		d := WebhookDelivery{
+			ID:          uuid.NewString(),
+			WebhookID:   hooks[i].ID,
+			EventID:     e.ID,
+			Event:       e.Type,
+			Payload:     payload,
+			Status:      DeliveryPending,
+			NextAttempt: now.Unix(),
+			CreatedAt:   now.Unix(),
+		}
+		if err := s.Set(SystemPersona, AppID, DeliveryKeyPrefix+d.ID, d); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+func listDeliveries(s CelerixStore, keep func(*WebhookDelivery) bool) ([]WebhookDelivery, error) {
+	keys, err := GetPersonaKeys(s, SystemPersona)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []WebhookDelivery{}
+	for k := range keys {
+		if !strings.HasPrefix(k, DeliveryKeyPrefix) {
+			continue
+		}
+		d, err := sdk.Get[WebhookDelivery](s, SystemPersona, AppID, k)
+		if err == nil && keep(&d) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt != deliveries[j].CreatedAt {
+			return deliveries[i].CreatedAt > deliveries[j].CreatedAt
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first.
+func ListDeliveries(s CelerixStore, webhookID string) ([]WebhookDelivery, error) {
+	return listDeliveries(s, func(d *WebhookDelivery) bool { return d.WebhookID == webhookID })
+}
+
+// DueDeliveries returns the pending deliveries whose next attempt is due at
+// now, oldest first.
+func DueDeliveries(s CelerixStore, now time.Time) ([]WebhookDelivery, error) {
+	due, err := listDeliveries(s, func(d *WebhookDelivery) bool {
+		return d.Status == DeliveryPending && d.NextAttempt <= now.Unix()
+	})
+	slices.Reverse(due)
+	return due, err
+}
+
+// DeliveryAttempted records the outcome of an attempt. A failed one is
+// retried with an exponential backoff until MaxWebhookAttempts; finished
+// deliveries stay in the log until maxDeliveryLog newer ones push them out.
+func DeliveryAttempted(s CelerixStore, id string, status int, sendErr error, now time.Time) (WebhookDelivery, error) {
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	d, err := sdk.Get[WebhookDelivery](s, SystemPersona, AppID, DeliveryKeyPrefix+id)
+	if err != nil {
+		return WebhookDelivery{}, storeError(err, "Webhook delivery not found")
+	}
+	d.Attempts++
+	d.ResponseStatus = status
+	d.NextAttempt = 0
+	switch {
+	case sendErr == nil:
+		d.Status = DeliveryDelivered
+		d.DeliveredAt = now.Unix()
+		d.LastError = ""
+	case d.Attempts >= MaxWebhookAttempts:
+		d.Status = DeliveryFailed
+		d.LastError = sendErr.Error()
+	default:
+		d.LastError = sendErr.Error()
+		d.NextAttempt = now.Add(retryDelay(webhookRetryBase, webhookRetryMax, d.Attempts)).Unix()
+	}
+	if err := s.Set(SystemPersona, AppID, DeliveryKeyPrefix+d.ID, d); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if d.Status != DeliveryPending {
+		return d, pruneDeliveries(s, d.WebhookID)
+	}
+	return d, nil
+}
+
+// pruneDeliveries drops the finished deliveries of a webhook beyond
+// maxDeliveryLog.
+func pruneDeliveries(s CelerixStore, webhookID string) error {
+	finished, err := listDeliveries(s, func(d *WebhookDelivery) bool {
+		return d.WebhookID == webhookID && d.Status != DeliveryPending
+	})
+	if err != nil || len(finished) <= maxDeliveryLog {
+		return err
+	}
+	for _, d := range finished[maxDeliveryLog:] {
+		if err := s.Delete(SystemPersona, AppID, DeliveryKeyPrefix+d.ID); err != nil && !isMissing(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Package events fans out events to the live streams clients hold open.
+package events
+
+import "sync"
+
+// buffer is how many events a stream may fall behind before it misses
+// some.
+const buffer = 16
+
+// Event is a message for a client. Type names it on the stream.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Bus delivers events to the subscribers of a client. The zero value is not
+// usable; a nil *Bus drops every event, so publishers need no checks.
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan Event]struct{}
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[string]map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the events published for clientID
+// and a function that ends the subscription. The channel is closed when the
+// subscription ends or the bus is closed.
+func (b *Bus) Subscribe(clientID string) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[clientID] == nil {
+		b.subs[clientID] = map[chan Event]struct{}{}
+	}
+	b.subs[clientID][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[clientID][ch]; !ok {
+			return
+		}
+		delete(b.subs[clientID], ch)
+		if len(b.subs[clientID]) == 0 {
+			delete(b.subs, clientID)
+		}
+		close(ch)
+	}
+}
+
+// Close ends every subscription, so open streams finish, and refuses new
+// ones. It is meant for server shutdown.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for clientID, chans := range b.subs {
+		for ch := range chans {
+			close(ch)
+		}
+		delete(b.subs, clientID)
+	}
+	b.closed = true
+}
+
+// Publish delivers e to every subscriber of clientID. A subscriber that is
+// not keeping up misses the event rather than holding up the publisher.
+func (b *Bus) Publish(clientID string, e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[clientID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions of clientID.
+func (b *Bus) Subscribers(clientID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[clientID])
+}
@@ -0,0 +1,54 @@
+package events
+
+import "testing"
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+	first, cancelFirst := b.Subscribe("alice")
+	second, cancelSecond := b.Subscribe("alice")
+	other, cancelOther := b.Subscribe("bob")
+	defer cancelOther()
+
+	b.Publish("alice", Event{Type: "ping"})
+	for _, ch := range []<-chan Event{first, second} {
+		if e := <-ch; e.Type != "ping" {
+			t.Errorf("expected ping, got %+v", e)
+		}
+	}
+	select {
+	case e := <-other:
+		t.Errorf("expected nothing for bob, got %+v", e)
+	default:
+	}
+
+	cancelFirst()
+	cancelFirst()
+	if _, open := <-first; open {
+		t.Error("expected the channel to be closed")
+	}
+	if n := b.Subscribers("alice"); n != 1 {
+		t.Errorf("expected one subscriber left, got %d", n)
+	}
+
+	// A stream that does not read misses events instead of blocking
+	for range buffer + 5 {
+		b.Publish("alice", Event{Type: "flood"})
+	}
+	if len(second) != buffer {
+		t.Errorf("expected a full buffer, got %d", len(second))
+	}
+	cancelSecond()
+
+	last, cancelLast := b.Subscribe("carol")
+	defer cancelLast()
+	b.Close()
+	if _, open := <-last; open {
+		t.Error("expected Close to end open subscriptions")
+	}
+	if _, open := <-func() <-chan Event { ch, _ := b.Subscribe("carol"); return ch }(); open {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+
+	var none *Bus
+	none.Publish("alice", Event{Type: "dropped"})
+}
@@ -0,0 +1,250 @@
+// Package fsck reconciles file records in the store with the blobs stored in
+// STORAGE_DIR and optionally repairs what does not line up.
+package fsck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-flow/internal/storage"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+type IssueKind string
+
+const (
+	// OrphanBlob is a file in the storage directory no record points to.
+	OrphanBlob IssueKind = "orphan_blob"
+	// DanglingRecord is a file record whose blob is missing.
+	DanglingRecord IssueKind = "dangling_record"
+	// MissingOwner is a file record owned by a client that no longer exists.
+	MissingOwner IssueKind = "missing_owner"
+	// SizeMismatch is a file record whose size differs from the blob on disk.
+	SizeMismatch IssueKind = "size_mismatch"
+	// UnreadableBlob is a file record whose blob cannot be checked, for
+	// example because of its permissions. It is never repaired.
+	UnreadableBlob IssueKind = "unreadable_blob"
+)
+
+// orphanGrace is how long a blob without record is left alone: uploads store
+// the blob before they save its record.
+const orphanGrace = time.Hour
+
+type Issue struct {
+	Kind     IssueKind `json:"kind"`
+	FileID   string    `json:"file_id,omitempty"`
+	OwnerID  string    `json:"owner_id,omitempty"`
+	Path     string    `json:"path"`
+	Detail   string    `json:"detail"`
+	Repaired bool      `json:"repaired"`
+}
+
+type Report struct {
+	Records int     `json:"records"`
+	Blobs   int     `json:"blobs"`
+	Issues  []Issue `json:"issues"`
+	Repair  bool    `json:"repair"`
+}
+
+// Unresolved counts the issues that are still present after the run.
+func (r *Report) Unresolved() int {
+	n := 0
+	for _, i := range r.Issues {
+		if !i.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// located is a file record together with where it is stored.
+type located struct {
+	persona string
+	key     string
+	record  db.FileRecord
+}
+
+// Run checks every live and trashed file record against the storage
+// directory. With repair set, orphan blobs and dangling records are deleted,
+// records of missing owners are handed to the admin and sizes are corrected.
+// Blobs written within the last hour are not reported as orphans.
+func Run(s db.CelerixStore, storageDir string, repair bool) (*Report, error) {
+	records, err := loadRecords(s)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := listBlobs(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Records: len(records), Blobs: len(blobs), Issues: []Issue{}, Repair: repair}
+	referenced := make(map[string]bool)
+
+	for _, l := range records {
+		r := l.record
+		path := cleanPath(r.StoredPath)
+		referenced[path] = true
+
+		info, statErr := os.Stat(r.StoredPath)
+		if statErr != nil && !errors.Is(statErr, fs.ErrNotExist) {
+			report.Issues = append(report.Issues, Issue{
+				Kind:    UnreadableBlob,
+				FileID:  r.ID,
+				OwnerID: r.OwnerID,
+				Path:    r.StoredPath,
+				Detail:  statErr.Error(),
+			})
+			continue
+		}
+		if statErr != nil {
+			issue := Issue{
+				Kind:    DanglingRecord,
+				FileID:  r.ID,
+				OwnerID: r.OwnerID,
+				Path:    r.StoredPath,
+				Detail:  fmt.Sprintf("blob for %q is missing", r.OriginalName),
+			}
+			if repair {
+				issue.Repaired = s.Delete(l.persona, db.AppID, l.key) == nil
+			}
+			report.Issues = append(report.Issues, issue)
+			// Nothing else to check for a record that is gone
+			continue
+		}
+
+		if info.Size() != r.Size {
+			issue := Issue{
+				Kind:    SizeMismatch,
+				FileID:  r.ID,
+				OwnerID: r.OwnerID,
+				Path:    r.StoredPath,
+				Detail:  fmt.Sprintf("record says %d bytes, blob has %d", r.Size, info.Size()),
+			}
+			if repair {
+				r.Size = info.Size()
+				issue.Repaired = s.Set(l.persona, db.AppID, l.key, r) == nil
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+
+		if r.OwnerID != "" && !ownerExists(s, r.OwnerID) {
+			issue := Issue{
+				Kind:    MissingOwner,
+				FileID:  r.ID,
+				OwnerID: r.OwnerID,
+				Path:    r.StoredPath,
+				Detail:  fmt.Sprintf("owner %s does not exist", r.OwnerID),
+			}
+			if repair {
+				issue.Repaired = reassignToAdmin(s, l, r) == nil
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	for _, path := range blobs {
+		if referenced[path] {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || time.Since(info.ModTime()) < orphanGrace {
+			// Gone meanwhile, or an upload that has not saved its record yet
+			continue
+		}
+		issue := Issue{
+			Kind:   OrphanBlob,
+			Path:   path,
+			Detail: "no file record references this blob",
+		}
+		if repair {
+			issue.Repaired = storage.DeleteFile(path) == nil
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	return report, nil
+}
+
+func loadRecords(s db.CelerixStore) ([]located, error) {
+	allData, err := s.DumpApp(db.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []located
+	for personaID, appStore := range allData {
+		for k := range appStore {
+			if !strings.HasPrefix(k, db.FileKeyPrefix) && !strings.HasPrefix(k, db.TrashFileKeyPrefix) {
+				continue
+			}
+			r, err := sdk.Get[db.FileRecord](s, personaID, db.AppID, k)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s/%s: %w", personaID, k, err)
+			}
+			records = append(records, located{persona: personaID, key: k, record: r})
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].key < records[j].key
+	})
+	return records, nil
+}
+
+func listBlobs(storageDir string) ([]string, error) {
+	entries, err := os.ReadDir(storageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var blobs []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			blobs = append(blobs, cleanPath(filepath.Join(storageDir, e.Name())))
+		}
+	}
+	return blobs, nil
+}
+
+func cleanPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+// ownerExists treats trashed clients as existing: their files are handled by
+// the trash purger.
+func ownerExists(s db.CelerixStore, id string) bool {
+	if _, err := db.GetClient(s, id); err == nil {
+		return true
+	}
+	_, err := db.GetTrashedClient(s, id)
+	return err == nil
+}
+
+func reassignToAdmin(s db.CelerixStore, l located, r db.FileRecord) error {
+	tx := db.NewTx(s)
+	r.OwnerID = ""
+	r.OwnerName = ""
+	if err := tx.Set(db.SystemPersona, l.key, r); err != nil {
+		return err
+	}
+	if err := tx.Delete(l.persona, l.key); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, FormatJSON)
+	logger.Info("recover", "recovery_code", "ABCD1234", "admin_secret", "hunter2", "client_id", "client-1", "name", "Alice")
+
+	out := buf.String()
+	for _, leaked := range []string{"ABCD1234", "hunter2", "client-1"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q to be redacted: %s", leaked, out)
+		}
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["client_id"] != Fingerprint("client-1") {
+		t.Errorf("expected client ID fingerprint, got %v", line["client_id"])
+	}
+	if line["name"] != "Alice" {
+		t.Errorf("expected other attributes to be kept, got %v", line["name"])
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(New(&buf, slog.LevelDebug, FormatJSON))
+	defer slog.SetDefault(prev)
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/files/:id", func(c *gin.Context) {
+		FromContext(c.Request.Context()).Info("inside handler")
+		c.Status(http.StatusOK)
+	})
+
+	// A valid incoming ID is kept and echoed
+	req, _ := http.NewRequest("GET", "/files/secret-link", nil)
+	req.Header.Set(RequestIDHeader, "edge-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got != "edge-42" {
+		t.Errorf("expected incoming request ID to be echoed, got %q", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected handler and access log lines, got %d", len(lines))
+	}
+	for _, l := range lines {
+		if !strings.Contains(l, `"request_id":"edge-42"`) {
+			t.Errorf("expected request ID in every line: %s", l)
+		}
+	}
+	if strings.Contains(buf.String(), "secret-link") {
+		t.Error("expected the access log to use the route pattern, not the path")
+	}
+
+	// An ID that could forge log lines is replaced
+	req, _ = http.NewRequest("GET", "/files/x", nil)
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(RequestIDHeader); got == "" || strings.Contains(got, "bad") {
+		t.Errorf("expected a generated request ID, got %q", got)
+	}
+}
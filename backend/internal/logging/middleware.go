@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 64
+
+// quietRoutes are polled by monitoring; successful calls are logged at
+// debug level only.
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// validRequestID accepts IDs from a proxy in front of flow as long as they
+// are short and cannot forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware assigns every request an ID, taken from X-Request-ID when the
+// caller sent a valid one, returns it in the response header and stores a
+// logger tagged with it in the request context. When the request is done it
+// writes one access log line. Only the route pattern is logged, since paths
+// of matched routes may hold download links.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, id)
+
+		logger := slog.Default().With("request_id", id)
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, "route", route)
+		} else {
+			attrs = append(attrs, "path", c.Request.URL.Path)
+		}
+		if clientID := c.GetHeader("X-Client-ID"); clientID != "" {
+			attrs = append(attrs, "client_id", clientID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case c.Writer.Status() >= 500:
+			level = slog.LevelError
+		case c.Writer.Status() >= 400:
+			level = slog.LevelWarn
+		case quietRoutes[c.FullPath()]:
+			level = slog.LevelDebug
+		}
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
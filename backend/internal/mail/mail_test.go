@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/celerix-dev/celerix-flow/internal/mail/smtptest"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]any{
+		"Recipient":    "Bob",
+		"Overdue":      true,
+		"Notification": map[string]string{"Title": "Ship <it>", "DueDate": "2026-01-02"},
+	}
+	msg, err := Render(TemplateDueReminder, "bob@example.com", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Overdue: Ship <it>" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Ship <it>") || !strings.Contains(msg.Text, "2026-01-02") {
+		t.Errorf("unexpected text body %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Ship &lt;it&gt;") {
+		t.Errorf("expected an escaped title in %q", msg.HTML)
+	}
+
+	for _, name := range []string{TemplateAssignment, TemplateShareLink} {
+		if _, err := Render(name, "bob@example.com", data); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if !strings.HasPrefix(msg.Text, "Hi Bob,") {
+		t.Errorf("expected the recipient's name in %q", msg.Text)
+	}
+	data["Recipient"] = ""
+	if msg, err := Render(TemplateAssignment, "bob@example.com", data); err != nil || !strings.HasPrefix(msg.Text, "Hi there,") {
+		t.Errorf("expected a neutral greeting without a name, got %q (%v)", msg.Text, err)
+	}
+	if _, err := Render("nope", "bob@example.com", data); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	sender := &SMTP{Addr: srv.Addr, From: "flow@example.com", TLS: TLSNone}
+	msg := Message{To: "bob@example.com", Subject: "Grüße", Text: "plain body\n", HTML: "<p>html body</p>\n"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := srv.Messages()
+	if len(got) != 1 {
+		t.Fatalf("expected one message, got %d", len(got))
+	}
+	if got[0].From != "flow@example.com" || len(got[0].To) != 1 || got[0].To[0] != "bob@example.com" {
+		t.Errorf("unexpected envelope %+v", got[0])
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Grüße" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(body))
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], "plain body") || !strings.Contains(bodies[1], "<p>html body</p>") {
+		t.Errorf("unexpected parts %q", bodies)
+	}
+
+	// Temporary failures come back as errors so the caller can retry
+	srv.Fail(1)
+	if err := sender.Send(context.Background(), msg); err == nil {
+		t.Error("expected an error from a failing server")
+	}
+
+	// The default mode refuses servers without STARTTLS
+	sender.TLS = ""
+	if err := sender.Send(context.Background(), msg); err == nil {
+		t.Error("expected an error without STARTTLS")
+	}
+}
@@ -0,0 +1,146 @@
+// Package smtptest runs a minimal SMTP server that keeps the messages it
+// receives, for tests of code that sends mail.
+package smtptest
+
+import (
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the server.
+type Message struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Server accepts plain SMTP connections on a local port. It offers neither
+// STARTTLS nor AUTH.
+type Server struct {
+	// Addr is host:port of the listener.
+	Addr string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Message
+	failures int
+}
+
+// NewServer starts a server on a random local port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Close stops the server and waits for its connections to end.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Fail makes the next n transactions fail with a temporary error.
+func (s *Server) Fail(n int) {
+	s.mu.Lock()
+	s.failures = n
+	s.mu.Unlock()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *Server) handle(c *textproto.Conn) {
+	reply := func(format string, args ...any) bool {
+		return c.PrintfLine(format, args...) == nil
+	}
+	if !reply("220 smtptest ESMTP") {
+		return
+	}
+
+	var msg Message
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-smtptest")
+			reply("250 8BITMIME")
+		case "MAIL":
+			msg = Message{From: address(arg)}
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			fail := s.failures > 0
+			if fail {
+				s.failures--
+			} else {
+				s.messages = append(s.messages, msg)
+			}
+			s.mu.Unlock()
+			if fail {
+				reply("451 Try again later")
+			} else {
+				reply("250 OK")
+			}
+		case "RSET":
+			msg = Message{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// address returns the address of a FROM:<a> or TO:<a> argument.
+func address(arg string) string {
+	_, a, _ := strings.Cut(arg, ":")
+	a, _, _ = strings.Cut(strings.TrimSpace(a), " ")
+	return strings.Trim(a, "<>")
+}
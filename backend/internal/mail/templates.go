@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names.
+const (
+	TemplateDueReminder = "due_reminder"
+	TemplateAssignment  = "assignment"
+	TemplateShareLink   = "share_link"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// template is one parsed template file. Every file defines the blocks
+// subject, text and html; the html block is escaped, the others are not.
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var templates = func() map[string]template {
+	out := map[string]template{}
+	for _, name := range []string{TemplateDueReminder, TemplateAssignment, TemplateShareLink} {
+		pattern := "templates/" + name + ".tmpl"
+		out[name] = template{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS, pattern)),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, pattern)),
+		}
+	}
+	return out
+}()
+
+// Render builds the message of template name with data for the address to.
+func Render(name, to string, data any) (Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.ExecuteTemplate(&html, "html", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    strings.TrimSpace(html.String()) + "\n",
+	}, nil
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/gin-gonic/gin"
+)
+
+// Default is the registry served on /metrics.
+var Default = NewRegistry()
+
+var (
+	HTTPRequests = Default.NewCounterVec("flow_http_requests_total",
+		"HTTP requests by method, route and status code.", "method", "route", "status")
+	HTTPDuration = Default.NewHistogramVec("flow_http_request_duration_seconds",
+		"HTTP request latency by method and route.", DefaultBuckets, "method", "route")
+	UploadBytes = Default.NewCounterVec("flow_upload_bytes_total",
+		"Bytes received through file uploads.")
+	StoreDuration = Default.NewHistogramVec("flow_store_operation_duration_seconds",
+		"Latency of celerix store operations by operation.", DefaultBuckets, "op")
+	StoreErrors = Default.NewCounterVec("flow_store_operation_errors_total",
+		"Failed celerix store operations by operation, not counting missing keys.", "op")
+)
+
+// unmatchedRoute labels requests no route matched, so arbitrary paths do
+// not create new series.
+const unmatchedRoute = "unmatched"
+
+// Middleware records count and latency of every request by route pattern.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		HTTPRequests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		HTTPDuration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// Handler serves the default registry.
+func Handler(c *gin.Context) {
+	c.Header("Content-Type", ContentType)
+	c.Status(200)
+	Default.Write(c.Writer)
+}
+
+// Store measures the latency of every operation of the wrapped store.
+type Store struct {
+	sdk.CelerixStore
+}
+
+// InstrumentStore wraps s so its operations show up in StoreDuration.
+func InstrumentStore(s sdk.CelerixStore) *Store {
+	return &Store{CelerixStore: s}
+}
+
+// observe records an operation. Lookups of missing keys, apps or personas are
+// regular answers, not failures.
+func observe(op string, start time.Time, err error) {
+	StoreDuration.Observe(time.Since(start).Seconds(), op)
+	if err != nil && !strings.HasSuffix(err.Error(), "not found") {
+		StoreErrors.Inc(op)
+	}
+}
+
+func (s *Store) Get(personaID, appID, key string) (v any, err error) {
+	defer func(start time.Time) { observe("get", start, err) }(time.Now())
+	return s.CelerixStore.Get(personaID, appID, key)
+}
+
+func (s *Store) Set(personaID, appID, key string, val any) (err error) {
+	defer func(start time.Time) { observe("set", start, err) }(time.Now())
+	return s.CelerixStore.Set(personaID, appID, key, val)
+}
+
+func (s *Store) Delete(personaID, appID, key string) (err error) {
+	defer func(start time.Time) { observe("delete", start, err) }(time.Now())
+	return s.CelerixStore.Delete(personaID, appID, key)
+}
+
+func (s *Store) GetPersonas() (p []string, err error) {
+	defer func(start time.Time) { observe("get_personas", start, err) }(time.Now())
+	return s.CelerixStore.GetPersonas()
+}
+
+func (s *Store) GetApps(personaID string) (a []string, err error) {
+	defer func(start time.Time) { observe("get_apps", start, err) }(time.Now())
+	return s.CelerixStore.GetApps(personaID)
+}
+
+func (s *Store) GetAppStore(personaID, appID string) (m map[string]any, err error) {
+	defer func(start time.Time) { observe("get_app_store", start, err) }(time.Now())
+	return s.CelerixStore.GetAppStore(personaID, appID)
+}
+
+func (s *Store) DumpApp(appID string) (m map[string]map[string]any, err error) {
+	defer func(start time.Time) { observe("dump_app", start, err) }(time.Now())
+	return s.CelerixStore.DumpApp(appID)
+}
+
+func (s *Store) GetGlobal(appID, key string) (v any, persona string, err error) {
+	defer func(start time.Time) { observe("get_global", start, err) }(time.Now())
+	return s.CelerixStore.GetGlobal(appID, key)
+}
+
+func (s *Store) Move(srcPersona, dstPersona, appID, key string) (err error) {
+	defer func(start time.Time) { observe("move", start, err) }(time.Now())
+	return s.CelerixStore.Move(srcPersona, dstPersona, appID, key)
+}
@@ -0,0 +1,348 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// GenerateClient renders the operations and schemas of doc as Go code of
+// package pkg. The output builds on the hand-written core of pkg/client:
+// the Client type, its do and stream methods and the payload helpers.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := &generator{doc: doc, imports: map[string]bool{"context": true}}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.schema(name, doc.Components.Schemas[name])
+	}
+
+	for _, r := range doc.Routes() {
+		if err := g.operation(r); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen from the OpenAPI document of %s %s. DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n")
+	out.Write(g.body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated client: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	doc     *Document
+	body    bytes.Buffer
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// schema emits a struct for an object schema. Schemas with binary
+// properties only describe multipart uploads and get no type.
+func (g *generator) schema(name string, s *Schema) {
+	if s.Type != "object" || len(s.Properties) == 0 {
+		return
+	}
+	for _, p := range s.Properties {
+		if p.Schema.Format == "binary" {
+			return
+		}
+	}
+
+	g.printf("\n")
+	g.comment(s.Description)
+	g.printf("type %s struct {\n", name)
+	for _, p := range s.Properties {
+		required := contains(s.Required, p.Name)
+		typ := goType(p.Schema)
+		tag := p.Name
+		if !required {
+			tag += ",omitempty"
+			if p.Schema.Ref != "" {
+				typ = "*" + typ
+			}
+		}
+		if p.Schema.Description != "" {
+			g.printf("\t// %s.\n", p.Schema.Description)
+		}
+		if len(p.Schema.Enum) > 0 {
+			g.printf("\t// One of %s.\n", strings.Join(p.Schema.Enum, ", "))
+		}
+		g.printf("\t%s %s `json:\"%s\"`\n", goName(p.Name), typ, tag)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) operation(r Route) error {
+	op := r.Operation
+	if op.OperationID == "" {
+		return fmt.Errorf("%s %s: missing operationId", r.Method, r.Path)
+	}
+	name := goName(op.OperationID)
+
+	args := []string{"ctx context.Context"}
+	var query []Parameter
+	for _, p := range r.Parameters() {
+		switch p.In {
+		case "path":
+			args = append(args, argName(p.Name)+" string")
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	if len(query) > 0 {
+		g.printf("\n// %sParams are the query parameters of %s.\n", name, name)
+		g.printf("type %sParams struct {\n", name)
+		for _, p := range query {
+			if p.Description != "" {
+				g.printf("\t// %s\n", p.Description)
+			} else if len(p.Schema.Enum) > 0 {
+				g.printf("\t// One of %s.\n", strings.Join(p.Schema.Enum, ", "))
+			}
+			g.printf("\t%s %s\n", goName(p.Name), goType(p.Schema))
+		}
+		g.printf("}\n")
+		args = append(args, "params *"+name+"Params")
+	}
+
+	payload := "nil"
+	if rb := op.RequestBody; rb != nil {
+		switch {
+		case rb.Content["application/json"].Schema != nil:
+			args = append(args, "body "+goType(rb.Content["application/json"].Schema))
+			payload = "jsonPayload(body)"
+		case rb.Content["multipart/form-data"].Schema != nil:
+			field, err := g.fileField(rb.Content["multipart/form-data"].Schema)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+			args = append(args, "filename string", "file io.Reader")
+			payload = fmt.Sprintf("filePayload(%q, filename, file)", field)
+			g.imports["io"] = true
+		default:
+			return fmt.Errorf("%s: unsupported request body", op.OperationID)
+		}
+	}
+
+	resp, ok := g.doc.response(op, "200")
+	if !ok {
+		resp, ok = g.doc.response(op, "201")
+	}
+	if !ok {
+		return fmt.Errorf("%s: no 200 or 201 response", op.OperationID)
+	}
+	result, stream := "", false
+	for ct, mt := range resp.Content {
+		if ct == "application/json" {
+			result = goType(mt.Schema)
+		} else if mt.Schema != nil && mt.Schema.Format == "binary" {
+			result, stream = "io.ReadCloser", true
+			g.imports["io"] = true
+		}
+	}
+	if result == "" {
+		return fmt.Errorf("%s: unsupported response", op.OperationID)
+	}
+	pointer := resp.Content["application/json"].Schema != nil && resp.Content["application/json"].Schema.Ref != ""
+
+	g.printf("\n// %s calls %s %s.", name, r.Method, r.Path)
+	if op.Summary != "" {
+		g.printf(" %s.", op.Summary)
+	}
+	g.printf("\n")
+	if op.Description != "" {
+		g.printf("//\n")
+		g.comment(op.Description)
+	}
+	returns := result
+	if pointer {
+		returns = "*" + result
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), returns)
+
+	queryArg := "nil"
+	if len(query) > 0 {
+		queryArg = "q"
+		g.imports["net/url"] = true
+		g.printf("\tq := url.Values{}\n\tif params != nil {\n")
+		for _, p := range query {
+			field := "params." + goName(p.Name)
+			switch goType(p.Schema) {
+			case "string":
+				g.printf("\t\tif %s != \"\" {\n\t\t\tq.Set(%q, %s)\n\t\t}\n", field, p.Name, field)
+			case "int":
+				g.imports["strconv"] = true
+				g.printf("\t\tif %s != 0 {\n\t\t\tq.Set(%q, strconv.Itoa(%s))\n\t\t}\n", field, p.Name, field)
+			case "bool":
+				g.printf("\t\tif %s {\n\t\t\tq.Set(%q, \"true\")\n\t\t}\n", field, p.Name)
+			default:
+				return fmt.Errorf("%s: unsupported query parameter %s", op.OperationID, p.Name)
+			}
+		}
+		g.printf("\t}\n")
+	}
+
+	path := g.pathExpr(r.Path)
+	if stream {
+		g.printf("\treturn c.stream(ctx, %q, %s, %s, %s)\n}\n", r.Method, path, queryArg, payload)
+		return nil
+	}
+	g.printf("\tvar out %s\n", result)
+	g.printf("\tif err := c.do(ctx, %q, %s, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", r.Method, path, queryArg, payload)
+	if pointer {
+		g.printf("\treturn &out, nil\n}\n")
+	} else {
+		g.printf("\treturn out, nil\n}\n")
+	}
+	return nil
+}
+
+// pathExpr turns /files/{id} into "/files/" + url.PathEscape(id).
+func (g *generator) pathExpr(path string) string {
+	var parts []string
+	for path != "" {
+		before, rest, found := strings.Cut(path, "{")
+		if before != "" {
+			parts = append(parts, fmt.Sprintf("%q", before))
+		}
+		if !found {
+			break
+		}
+		param, after, _ := strings.Cut(rest, "}")
+		g.imports["net/url"] = true
+		parts = append(parts, "url.PathEscape("+argName(param)+")")
+		path = after
+	}
+	return strings.Join(parts, " + ")
+}
+
+// fileField returns the name of the binary property of a multipart schema.
+func (g *generator) fileField(s *Schema) (string, error) {
+	if s.Ref != "" {
+		s = g.doc.Components.Schemas[refName(s.Ref)]
+	}
+	if s != nil {
+		for _, p := range s.Properties {
+			if p.Schema.Format == "binary" {
+				return p.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("multipart body without a binary property")
+}
+
+func (g *generator) comment(text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		g.printf("// %s\n", line)
+	}
+}
+
+func goType(s *Schema) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + goType(s.AdditionalProperties)
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API", "http": "HTTP", "json": "JSON"}
+
+// goName turns snake_case and camelCase names into exported Go names.
+func goName(name string) string {
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if part[i] >= 'A' && part[i] <= 'Z' && part[i-1] >= 'a' && part[i-1] <= 'z' {
+				words = append(words, part[start:i])
+				start = i
+			}
+		}
+		words = append(words, part[start:])
+	}
+
+	var b strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		if upper, ok := initialisms[strings.ToLower(w)]; ok {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+func argName(name string) string {
+	n := goName(name)
+	if upper, ok := initialisms[strings.ToLower(n)]; ok && upper == n {
+		n = strings.ToLower(n)
+	} else {
+		n = strings.ToLower(n[:1]) + n[1:]
+	}
+	if token.IsKeyword(n) {
+		n += "_"
+	}
+	return n
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
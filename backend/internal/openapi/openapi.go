@@ -0,0 +1,234 @@
+// Package openapi reads the subset of OpenAPI 3 used by the Flow API
+// document and generates the Go client in pkg/client from it.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema  `json:"schemas"`
+	Responses map[string]Response `json:"responses"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Get        *Operation  `json:"get"`
+	Post       *Operation  `json:"post"`
+	Put        *Operation  `json:"put"`
+	Delete     *Operation  `json:"delete"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref         string     `json:"$ref"`
+	Type        string     `json:"type"`
+	Format      string     `json:"format"`
+	Description string     `json:"description"`
+	Enum        []string   `json:"enum"`
+	Items       *Schema    `json:"items"`
+	Required    []string   `json:"required"`
+	Properties  Properties `json:"properties"`
+	// AdditionalProperties types the values of map-like objects; only the
+	// schema form is supported, not true or false.
+	AdditionalProperties *Schema `json:"additionalProperties"`
+}
+
+// Property is a named schema property. Properties keep the order of the
+// document so generated structs read like the spec.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+type Properties []Property
+
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("properties: expected object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := tok.(string)
+		var s Schema
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		*p = append(*p, Property{Name: name, Schema: &s})
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// Parse decodes an OpenAPI document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Route is one operation of the document.
+type Route struct {
+	Method    string
+	Path      string
+	Item      PathItem
+	Operation *Operation
+}
+
+// Routes lists all operations sorted by path and method.
+func (d *Document) Routes() []Route {
+	paths := make([]string, 0, len(d.Paths))
+	for p := range d.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var routes []Route
+	for _, p := range paths {
+		item := d.Paths[p]
+		for _, m := range []struct {
+			method string
+			op     *Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"DELETE", item.Delete}} {
+			if m.op != nil {
+				routes = append(routes, Route{Method: m.method, Path: p, Item: item, Operation: m.op})
+			}
+		}
+	}
+	return routes
+}
+
+// Parameters returns the path level parameters followed by the operation's.
+func (r Route) Parameters() []Parameter {
+	return append(append([]Parameter{}, r.Item.Parameters...), r.Operation.Parameters...)
+}
+
+// Refs returns every $ref in the document.
+func (d *Document) Refs() []string {
+	var refs []string
+	var walk func(s *Schema)
+	walk = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			refs = append(refs, s.Ref)
+		}
+		walk(s.Items)
+		walk(s.AdditionalProperties)
+		for _, p := range s.Properties {
+			walk(p.Schema)
+		}
+	}
+	content := func(c map[string]MediaType) {
+		for _, mt := range c {
+			walk(mt.Schema)
+		}
+	}
+
+	for _, r := range d.Routes() {
+		for _, p := range r.Parameters() {
+			walk(p.Schema)
+		}
+		if r.Operation.RequestBody != nil {
+			content(r.Operation.RequestBody.Content)
+		}
+		for _, resp := range r.Operation.Responses {
+			if resp.Ref != "" {
+				refs = append(refs, resp.Ref)
+			}
+			content(resp.Content)
+		}
+	}
+	for _, s := range d.Components.Schemas {
+		walk(s)
+	}
+	for _, resp := range d.Components.Responses {
+		content(resp.Content)
+	}
+	return refs
+}
+
+// Resolve reports whether ref points into the components of the document.
+func (d *Document) Resolve(ref string) bool {
+	switch {
+	case strings.HasPrefix(ref, "#/components/schemas/"):
+		_, ok := d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+		return ok
+	case strings.HasPrefix(ref, "#/components/responses/"):
+		_, ok := d.Components.Responses[strings.TrimPrefix(ref, "#/components/responses/")]
+		return ok
+	}
+	return false
+}
+
+// response returns the response of an operation for the status, following
+// a reference to the shared responses.
+func (d *Document) response(op *Operation, status string) (Response, bool) {
+	resp, ok := op.Responses[status]
+	if ok && resp.Ref != "" {
+		resp, ok = d.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+	}
+	return resp, ok
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
@@ -0,0 +1,87 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Test", "version": "1"},
+  "paths": {
+    "/items/{item_id}/tags": {
+      "parameters": [{"name": "item_id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "operationId": "listTags",
+        "parameters": [{"name": "limit", "in": "query", "schema": {"type": "integer"}}],
+        "responses": {"200": {"description": "Tags", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Tag"}}}}}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Tag": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}, "owner_id": {"type": "string"}, "color": {"type": "string"}}},
+      "Labels": {"type": "object", "properties": {"labels": {"type": "object", "additionalProperties": {"type": "string"}}}}
+    }
+  }
+}`
+
+func TestParseKeepsPropertyOrder(t *testing.T) {
+	doc, err := Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range doc.Components.Schemas["Tag"].Properties {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "name,owner_id,color" {
+		t.Errorf("expected document order, got %v", names)
+	}
+	for _, ref := range doc.Refs() {
+		if !doc.Resolve(ref) {
+			t.Errorf("unresolved %s", ref)
+		}
+	}
+	if doc.Resolve("#/components/schemas/Missing") {
+		t.Error("expected a missing schema not to resolve")
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	doc, err := Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateClient(doc, "testclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package testclient",
+		"Name    string `json:\"name\"`",
+		"OwnerID string `json:\"owner_id,omitempty\"`",
+		"Labels map[string]string `json:\"labels,omitempty\"`",
+		"func (c *Client) ListTags(ctx context.Context, itemID string, params *ListTagsParams) ([]Tag, error)",
+		`"/items/"+url.PathEscape(itemID)+"/tags"`,
+		`q.Set("limit", strconv.Itoa(params.Limit))`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code lacks %s:\n%s", want, src)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"owner_id":     "OwnerID",
+		"getOpenAPI":   "GetOpenAPI",
+		"is_public":    "IsPublic",
+		"download_url": "DownloadURL",
+		"id":           "ID",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package search keeps an in-process inverted index over cards, projects and
+// files. The index is fed by Store, which wraps the celerix store and
+// reindexes every flow key written through it.
+package search
+
+import (
+	"html"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Document kinds.
+const (
+	KindCard    = "card"
+	KindProject = "project"
+	KindFile    = "file"
+)
+
+// prefixWeight scales matches of a query term that is only a prefix of the
+// indexed word, so "plan" ranks "plan" above "planning".
+const prefixWeight = 0.5
+
+// snippetRunes is the length of highlighted snippets of long fields.
+const snippetRunes = 160
+
+// Field is a searchable text of a document. Weight scales its matches
+// against those of other fields.
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Document is an indexed item. Owner, ProjectID and Public carry what
+// callers need to decide who may see it.
+type Document struct {
+	Kind      string
+	ID        string
+	Owner     string
+	ProjectID string
+	Public    bool
+	Title     string
+	Fields    []Field
+}
+
+func (d *Document) key() string {
+	return d.Kind + "\x00" + d.Owner + "\x00" + d.ID
+}
+
+// Result is a document matching a query with its score and highlighted
+// fields. Highlights hold HTML-escaped text with matches in <mark>. The
+// owner is left out: client IDs are credentials.
+type Result struct {
+	Kind       string            `json:"kind"`
+	ID         string            `json:"id"`
+	ProjectID  string            `json:"project_id,omitempty"`
+	Title      string            `json:"title"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Query selects documents containing every term of Text, as a word or word
+// prefix. Kinds limits the result to some kinds and Visible, if set, to the
+// documents it accepts.
+type Query struct {
+	Text    string
+	Kinds   []string
+	Visible func(*Document) bool
+	Limit   int
+}
+
+type indexed struct {
+	doc   Document
+	terms map[string]float64
+}
+
+// Index is an inverted index safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*indexed
+	postings map[string]map[string]float64
+	// terms are the keys of postings, sorted for prefix lookups
+	terms []string
+	// sources maps a store key to the documents indexed from it
+	sources map[string][]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[string]*indexed{},
+		postings: map[string]map[string]float64{},
+		sources:  map[string][]string{},
+	}
+}
+
+// Replace swaps the documents indexed from source for docs.
+func (x *Index) Replace(source string, docs []Document) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, key := range x.sources[source] {
+		x.remove(key)
+	}
+	delete(x.sources, source)
+
+	keys := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		key := doc.key()
+		x.remove(key)
+		entry := &indexed{doc: doc, terms: map[string]float64{}}
+		for _, f := range doc.Fields {
+			for _, tok := range tokenize(f.Text) {
+				entry.terms[tok.term] += f.Weight
+			}
+		}
+		for term, weight := range entry.terms {
+			if x.postings[term] == nil {
+				x.postings[term] = map[string]float64{}
+				i, _ := slices.BinarySearch(x.terms, term)
+				x.terms = slices.Insert(x.terms, i, term)
+			}
+			x.postings[term][key] = weight
+		}
+		x.docs[key] = entry
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		x.sources[source] = keys
+	}
+}
+
+func (x *Index) remove(key string) {
+	entry, ok := x.docs[key]
+	if !ok {
+		return
+	}
+	for term := range entry.terms {
+		delete(x.postings[term], key)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+			if i, ok := slices.BinarySearch(x.terms, term); ok {
+				x.terms = slices.Delete(x.terms, i, i+1)
+			}
+		}
+	}
+	delete(x.docs, key)
+}
+
+// Len returns the number of indexed documents.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Search returns the documents matching q, best first. Each term scores the
+// weighted frequency of the words it matches times their inverse document
+// frequency; prefix matches count less than whole words.
+func (x *Index) Search(q Query) []Result {
+	terms := uniqueTerms(q.Text)
+	if len(terms) == 0 {
+		return []Result{}
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	total := float64(len(x.docs))
+	var scores map[string]float64
+	for _, term := range terms {
+		matched := map[string]float64{}
+		start, _ := slices.BinarySearch(x.terms, term)
+		for _, word := range x.terms[start:] {
+			if !strings.HasPrefix(word, term) {
+				break
+			}
+			posting := x.postings[word]
+			factor := 1.0
+			if word != term {
+				factor = prefixWeight
+			}
+			idf := math.Log(1 + total/float64(len(posting)))
+			for key, weight := range posting {
+				matched[key] += factor * weight * idf
+			}
+		}
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for key, score := range scores {
+			if extra, ok := matched[key]; ok {
+				scores[key] = score + extra
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	results := []Result{}
+	for key, score := range scores {
+		doc := &x.docs[key].doc
+		if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, doc.Kind) {
+			continue
+		}
+		if q.Visible != nil && !q.Visible(doc) {
+			continue
+		}
+		results = append(results, Result{
+			Kind:       doc.Kind,
+			ID:         doc.ID,
+			ProjectID:  doc.ProjectID,
+			Title:      doc.Title,
+			Score:      math.Round(score*1000) / 1000,
+			Highlights: highlights(doc, terms),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.ID < b.ID
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lowercase words of letters and digits with
+// their byte offsets.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+func uniqueTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, tok := range tokenize(text) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			terms = append(terms, tok.term)
+		}
+	}
+	return terms
+}
+
+// highlights marks the words matching terms in every field that has one.
+// Long fields are cut to a snippet around the first match.
+func highlights(doc *Document, terms []string) map[string]string {
+	out := map[string]string{}
+	for _, f := range doc.Fields {
+		var matches []token
+		for _, tok := range tokenize(f.Text) {
+			for _, term := range terms {
+				if strings.HasPrefix(tok.term, term) {
+					matches = append(matches, tok)
+					break
+				}
+			}
+		}
+		if len(matches) > 0 {
+			out[f.Name] = mark(f.Text, matches)
+		}
+	}
+	return out
+}
+
+func mark(text string, matches []token) string {
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetRunes {
+		from = runeOffset(text, matches[0].start, -snippetRunes/4)
+		to = runeOffset(text, from, snippetRunes)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.start < pos || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// runeOffset moves n runes from the byte offset at, clamped to text.
+func runeOffset(text string, at, n int) int {
+	for ; n < 0 && at > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:at])
+		at -= size
+	}
+	for ; n > 0 && at < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[at:])
+		at += size
+	}
+	return at
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchRanksAndHighlights(t *testing.T) {
+	x := NewIndex()
+	x.Replace("board", []Document{
+		{Kind: KindCard, ID: "c1", Title: "Plan release", Fields: []Field{
+			{Name: "title", Text: "Plan release", Weight: 3},
+			{Name: "description", Text: "Write <notes> for the release", Weight: 1},
+		}},
+		{Kind: KindCard, ID: "c2", Title: "Planning meeting", Fields: []Field{
+			{Name: "title", Text: "Planning meeting", Weight: 3},
+		}},
+		{Kind: KindCard, ID: "c3", Title: "Other", Fields: []Field{
+			{Name: "title", Text: "Other", Weight: 3},
+			{Name: "description", Text: "plan", Weight: 1},
+		}},
+	})
+
+	results := x.Search(Query{Text: "plan"})
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	// Whole words beat prefixes, titles beat descriptions
+	if strings.Join(ids, ",") != "c1,c2,c3" {
+		t.Fatalf("unexpected ranking %v", ids)
+	}
+	if got := results[1].Highlights["title"]; got != "<mark>Planning</mark> meeting" {
+		t.Errorf("unexpected highlight %q", got)
+	}
+
+	results = x.Search(Query{Text: "PLAN notes"})
+	if len(results) != 1 || results[0].Highlights["description"] != "Write &lt;<mark>notes</mark>&gt; for the release" {
+		t.Errorf("expected every term to be required and text escaped, got %+v", results)
+	}
+
+	if results := x.Search(Query{Text: "plan", Visible: func(d *Document) bool { return d.ID == "c3" }}); len(results) != 1 {
+		t.Errorf("expected Visible to filter, got %+v", results)
+	}
+}
+
+func TestReplaceDropsOldDocuments(t *testing.T) {
+	x := NewIndex()
+	x.Replace("board", []Document{{Kind: KindCard, ID: "c1", Title: "Alpha", Fields: []Field{{Name: "title", Text: "Alpha", Weight: 1}}}})
+	x.Replace("board", []Document{{Kind: KindCard, ID: "c2", Title: "Beta", Fields: []Field{{Name: "title", Text: "Beta", Weight: 1}}}})
+	if len(x.Search(Query{Text: "alpha"})) != 0 || len(x.Search(Query{Text: "beta"})) != 1 || x.Len() != 1 {
+		t.Error("expected the board's documents to be replaced")
+	}
+	if !slices.Equal(x.terms, []string{"beta"}) {
+		t.Errorf("expected the sorted words to follow the postings, got %v", x.terms)
+	}
+	x.Replace("board", nil)
+	if x.Len() != 0 || len(x.postings) != 0 || len(x.terms) != 0 {
+		t.Errorf("expected an empty index, got %d documents and %d words", x.Len(), len(x.postings))
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 40) + "needle " + strings.Repeat("dolor sit ", 40)
+	got := mark(text, []token{{term: "needle", start: strings.Index(text, "needle"), end: strings.Index(text, "needle") + 6}})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "<mark>needle</mark>") {
+		t.Errorf("unexpected snippet %q", got)
+	}
+}
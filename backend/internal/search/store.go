@@ -0,0 +1,180 @@
+package search
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/celerix-dev/celerix-flow/internal/db"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+)
+
+// Field weights: names and titles count more than descriptions.
+const (
+	titleWeight = 3
+	textWeight  = 1
+)
+
+// Store keeps an index up to date with every flow key written through the
+// wrapped store.
+type Store struct {
+	sdk.CelerixStore
+	index *Index
+}
+
+// Watch wraps s so writes through it update idx.
+func Watch(s sdk.CelerixStore, idx *Index) *Store {
+	return &Store{CelerixStore: s, index: idx}
+}
+
+func (s *Store) Set(personaID, appID, key string, val any) error {
+	if err := s.CelerixStore.Set(personaID, appID, key, val); err != nil {
+		return err
+	}
+	if appID == db.AppID {
+		s.index.Update(personaID, key, val)
+	}
+	return nil
+}
+
+func (s *Store) Delete(personaID, appID, key string) error {
+	if err := s.CelerixStore.Delete(personaID, appID, key); err != nil {
+		return err
+	}
+	if appID == db.AppID {
+		s.index.Replace(source(personaID, key), nil)
+	}
+	return nil
+}
+
+func (s *Store) Move(srcPersona, dstPersona, appID, key string) error {
+	if err := s.CelerixStore.Move(srcPersona, dstPersona, appID, key); err != nil {
+		return err
+	}
+	if appID == db.AppID {
+		s.index.Replace(source(srcPersona, key), nil)
+		if val, err := s.CelerixStore.Get(dstPersona, appID, key); err == nil {
+			s.index.Update(dstPersona, key, val)
+		}
+	}
+	return nil
+}
+
+// Rebuild indexes every flow key of s from scratch.
+func (x *Index) Rebuild(s sdk.CelerixStore) error {
+	dump, err := s.DumpApp(db.AppID)
+	if err != nil && !strings.HasSuffix(err.Error(), "not found") {
+		return err
+	}
+
+	x.mu.Lock()
+	x.docs = map[string]*indexed{}
+	x.postings = map[string]map[string]float64{}
+	x.sources = map[string][]string{}
+	x.mu.Unlock()
+
+	for persona, keys := range dump {
+		for key, val := range keys {
+			x.Update(persona, key, val)
+		}
+	}
+	return nil
+}
+
+// Update reindexes the value stored under key of persona. Keys that hold
+// nothing searchable are ignored.
+func (x *Index) Update(persona, key string, val any) {
+	var docs []Document
+	var err error
+	switch {
+	case key == db.KanbanKey:
+		docs, err = cardDocuments(persona, val)
+	case key == db.ProjectsKey:
+		docs, err = projectDocuments(persona, val)
+	case strings.HasPrefix(key, db.FileKeyPrefix):
+		docs, err = fileDocuments(persona, val)
+	default:
+		return
+	}
+	if err != nil {
+		// The value stays stored; it just cannot be found until fixed
+		slog.Warn("search: cannot index value", "owner_id", persona, "key", key, "err", err)
+		docs = nil
+	}
+	x.Replace(source(persona, key), docs)
+}
+
+func source(persona, key string) string {
+	return persona + "\x00" + key
+}
+
+func cardDocuments(persona string, val any) ([]Document, error) {
+	board, err := db.DecodeKanban(val)
+	if err != nil {
+		return nil, err
+	}
+	var docs []Document
+	board.Cards(func(_ *db.KanbanColumn, card *db.KanbanCard) {
+		var checklist []string
+		for _, item := range card.Checklist {
+			checklist = append(checklist, item.Text)
+		}
+		docs = append(docs, Document{
+			Kind:      KindCard,
+			ID:        card.ID,
+			Owner:     persona,
+			ProjectID: card.ProjectID,
+			Title:     card.Title,
+			Fields: []Field{
+				{Name: "title", Text: card.Title, Weight: titleWeight},
+				{Name: "description", Text: card.Description, Weight: textWeight},
+				{Name: "checklist", Text: strings.Join(checklist, "\n"), Weight: textWeight},
+			},
+		})
+	})
+	return docs, nil
+}
+
+func projectDocuments(persona string, val any) ([]Document, error) {
+	data, err := db.DecodeProjects(val)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]Document, 0, len(data.Projects))
+	for _, p := range data.Projects {
+		docs = append(docs, Document{
+			Kind:      KindProject,
+			ID:        p.ID,
+			Owner:     persona,
+			ProjectID: p.ID,
+			Title:     p.Name,
+			Fields: []Field{
+				{Name: "name", Text: p.Name, Weight: titleWeight},
+				{Name: "tag", Text: p.Tag, Weight: titleWeight},
+				{Name: "description", Text: p.Description, Weight: textWeight},
+			},
+		})
+	}
+	return docs, nil
+}
+
+func fileDocuments(persona string, val any) ([]Document, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	var record db.FileRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+	return []Document{{
+		Kind:   KindFile,
+		ID:     record.ID,
+		Owner:  record.OwnerID,
+		Public: record.IsPublic,
+		Title:  record.OriginalName,
+		Fields: []Field{
+			{Name: "name", Text: record.OriginalName, Weight: titleWeight},
+		},
+	}}, nil
+}
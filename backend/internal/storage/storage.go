@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func StoreFile(reader io.Reader, storageDir, fileName string) (string, int64, error) {
@@ -33,6 +34,39 @@ func GetFile(filePath string) (io.ReadCloser, error) {
 	return os.Open(filePath)
 }
 
+// Within reports whether filePath, once cleaned, lies inside storageDir.
+// Paths of file records are only trusted to be deleted if they do.
+func Within(storageDir, filePath string) bool {
+	dir, err := filepath.Abs(storageDir)
+	if err != nil {
+		return false
+	}
+	p, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func DeleteFile(filePath string) error {
 	return os.Remove(filePath)
 }
+
+// stagedSuffix marks blobs that are pending deletion inside a transaction.
+const stagedSuffix = ".deleting"
+
+// StageDelete renames a blob out of the way so that its deletion can still be
+// undone with UnstageDelete. The returned path is removed with DeleteFile once
+// the surrounding operation has committed.
+func StageDelete(filePath string) (string, error) {
+	staged := filePath + stagedSuffix
+	if err := os.Rename(filePath, staged); err != nil {
+		return "", err
+	}
+	return staged, nil
+}
+
+func UnstageDelete(stagedPath string) error {
+	return os.Rename(stagedPath, strings.TrimSuffix(stagedPath, stagedSuffix))
+}
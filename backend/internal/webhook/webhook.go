@@ -0,0 +1,200 @@
+// Package webhook signs webhook payloads and posts them to subscribers.
+//
+// Every request carries the signature header
+//
+//	X-Flow-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256>
+//
+// where the HMAC is keyed with the subscription's secret and computed over
+// the timestamp, a dot and the raw body. Receivers recompute it and reject
+// old timestamps to guard against replays.
+//
+// Subscribers are reached on public addresses only unless a request allows
+// private ones, and redirects are not followed, so webhooks cannot be used
+// to probe the network the instance runs in.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Request headers.
+const (
+	HeaderEvent     = "X-Flow-Event"
+	HeaderDelivery  = "X-Flow-Delivery"
+	HeaderSignature = "X-Flow-Signature"
+)
+
+// DefaultTimeout bounds a delivery when the sender has no timeout.
+const DefaultTimeout = 10 * time.Second
+
+// ErrPrivateAddress is returned for subscribers on loopback, private,
+// link-local and other non-public addresses.
+var ErrPrivateAddress = errors.New("webhook destination is not a public address")
+
+// cgnat is the shared address space of RFC 6598.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// Public reports whether ip is a public unicast address.
+func Public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip)
+}
+
+// publicOnly is a net.Dialer Control function refusing connections to
+// non-public addresses. It runs on the resolved address, so host names
+// resolving to internal addresses are caught too.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Public(ap.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the signature header of body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+func mac(secret, ts string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// Verify checks a signature header against body. Signatures made more than
+// tolerance before or after now are rejected.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return errors.New("malformed signature header")
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return errors.New("signature timestamp out of tolerance")
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// Request is one delivery attempt. AllowPrivate lets it reach non-public
+// addresses; only subscribers configured by admins should get it.
+type Request struct {
+	URL          string
+	Secret       string
+	Event        string
+	DeliveryID   string
+	Body         []byte
+	AllowPrivate bool
+}
+
+// Sender posts signed payloads.
+type Sender struct {
+	// Timeout bounds a delivery; zero means DefaultTimeout.
+	Timeout   time.Duration
+	UserAgent string
+	// AllowPrivate lets every request reach non-public addresses.
+	AllowPrivate bool
+
+	once            sync.Once
+	public, private *http.Client
+}
+
+func (s *Sender) client(allowPrivate bool) *http.Client {
+	s.once.Do(func() {
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		build := func(control func(string, string, syscall.RawConn) error) *http.Client {
+			dialer := &net.Dialer{Timeout: timeout, Control: control}
+			return &http.Client{
+				Timeout: timeout,
+				// No proxy: it would dial on the subscriber's behalf
+				Transport: &http.Transport{
+					DialContext:         dialer.DialContext,
+					TLSHandshakeTimeout: timeout,
+					MaxIdleConnsPerHost: 2,
+				},
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+		}
+		s.public = build(publicOnly)
+		s.private = build(nil)
+	})
+	if allowPrivate || s.AllowPrivate {
+		return s.private
+	}
+	return s.public
+}
+
+// Send posts r and returns the response status. Anything but a 2xx
+// response is an error, so the delivery is retried.
+func (s *Sender) Send(ctx context.Context, r Request) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, r.Event)
+	req.Header.Set(HeaderDelivery, r.DeliveryID)
+	req.Header.Set(HeaderSignature, Sign(r.Secret, time.Now(), r.Body))
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+
+	resp, err := s.client(r.AllowPrivate).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	// Redirects are answered, not followed, and count as failures
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1_900_000_000, 0)
+	body := []byte(`{"type":"card.created"}`)
+	header := Sign("secret", now, body)
+
+	if err := Verify("secret", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	for name, check := range map[string]error{
+		"wrong secret": Verify("other", header, body, now, 5*time.Minute),
+		"changed body": Verify("secret", header, []byte(`{}`), now, 5*time.Minute),
+		"too old":      Verify("secret", header, body, now.Add(time.Hour), 5*time.Minute),
+		"malformed":    Verify("secret", "v1=abc", body, now, 5*time.Minute),
+	} {
+		if check == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	status := http.StatusNoContent
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s := &Sender{UserAgent: "flow-test"}
+	req := Request{URL: srv.URL, Secret: "secret", Event: "file.uploaded", DeliveryID: "d1", Body: []byte(`{"id":"e1"}`)}
+	if _, err := s.Send(context.Background(), req); !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("expected the loopback subscriber to be refused, got %v", err)
+	}
+	req.AllowPrivate = true
+	if code, err := s.Send(context.Background(), req); err != nil || code != http.StatusNoContent {
+		t.Fatalf("expected a delivery, got %d (%v)", code, err)
+	}
+	if got.Header.Get(HeaderEvent) != "file.uploaded" || got.Header.Get(HeaderDelivery) != "d1" || got.UserAgent() != "flow-test" {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if err := Verify("secret", got.Header.Get(HeaderSignature), gotBody, time.Now(), time.Minute); err != nil {
+		t.Errorf("expected a verifiable signature, got %v", err)
+	}
+
+	status = http.StatusBadGateway
+	if code, err := s.Send(context.Background(), req); err == nil || code != http.StatusBadGateway {
+		t.Errorf("expected an error for a 502, got %d (%v)", code, err)
+	}
+
+	// Redirects are not followed
+	got = nil
+	redirect := httptest.NewServer(http.RedirectHandler(srv.URL, http.StatusFound))
+	defer redirect.Close()
+	req.URL = redirect.URL
+	if code, err := s.Send(context.Background(), req); err == nil || code != http.StatusFound || got != nil {
+		t.Errorf("expected the redirect to fail the delivery, got %d (%v)", code, err)
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"224.0.0.1":        false,
+	} {
+		if got := Public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
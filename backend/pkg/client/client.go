@@ -0,0 +1,151 @@
+// Package client is a Go client for the Celerix Flow API.
+//
+// The operations in client_gen.go are generated from the OpenAPI document
+// served at /api/v1/openapi.json; run go generate after changing it.
+//
+//	c := client.New("https://flow.example.com/api/v1", clientID)
+//	files, err := c.ListFiles(ctx, &client.ListFilesParams{Search: "report"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls a Flow instance. BaseURL includes the /api/v1 prefix.
+type Client struct {
+	BaseURL  string
+	ClientID string
+	// Token is a personal API token. When set it is sent as a bearer token
+	// and the instance ignores ClientID.
+	Token      string
+	HTTPClient *http.Client
+	// UserAgent is sent with every request when set.
+	UserAgent string
+}
+
+func New(baseURL, clientID string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), ClientID: clientID}
+}
+
+// Error is returned for responses outside the 2xx range. APIError holds the
+// decoded error envelope; it is zero if the body was not one.
+type Error struct {
+	StatusCode int
+	APIError
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("flow: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("flow: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// payload is an encoded request body.
+type payload struct {
+	body        io.Reader
+	contentType string
+	err         error
+}
+
+func jsonPayload(v any) *payload {
+	data, err := json.Marshal(v)
+	return &payload{body: bytes.NewReader(data), contentType: "application/json", err: err}
+}
+
+func filePayload(field, filename string, r io.Reader) *payload {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile(field, filename)
+	if err == nil {
+		_, err = io.Copy(part, r)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	return &payload{body: &buf, contentType: w.FormDataContentType(), err: err}
+}
+
+// send performs a request and returns the response of a 2xx status. Other
+// statuses are turned into *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, p *payload) (*http.Response, error) {
+	var body io.Reader
+	if p != nil {
+		if p.err != nil {
+			return nil, fmt.Errorf("flow: encode request: %w", p.err)
+		}
+		body = p.body
+	}
+
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		req.Header.Set("Content-Type", p.contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.ClientID != "" {
+		req.Header.Set("X-Client-ID", c.ClientID)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var envelope ErrorResponse
+		if json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope) == nil {
+			apiErr.APIError = envelope.Error
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// do performs a request and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, p *payload, out any) error {
+	resp, err := c.send(ctx, method, path, query, p)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("flow: decode response: %w", err)
+	}
+	return nil
+}
+
+// stream performs a request and hands the response body to the caller, who
+// must close it.
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, p *payload) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, method, path, query, p)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
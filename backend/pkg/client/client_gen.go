@@ -0,0 +1,1239 @@
+// Code generated by clientgen from the OpenAPI document of Celerix Flow API 0.1.0. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+type APIError struct {
+	// One of bad_request, validation_failed, unauthorized, forbidden, not_found, conflict, gone, internal_error.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+type APIToken struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// One of read-only, read-write, files-only.
+	Scope string `json:"scope,omitempty"`
+	// Only returned on create; send it as Authorization: Bearer.
+	Token string `json:"token,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+	// Unix time in seconds, updated at most once a minute; absent if never used.
+	LastUsedAt int64 `json:"last_used_at,omitempty"`
+}
+
+type APITokenInput struct {
+	Name string `json:"name"`
+	// One of read-only, read-write, files-only.
+	Scope string `json:"scope"`
+}
+
+type APITokenList struct {
+	Tokens []APIToken `json:"tokens,omitempty"`
+}
+
+type AdminRequest struct {
+	Secret string `json:"secret"`
+}
+
+type ArchiveResult struct {
+	Status   string    `json:"status,omitempty"`
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+type BlobEntry struct {
+	Name string `json:"name,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+type BoardCard struct {
+	// Handle of the client whose board holds the card, as in Membership.
+	Owner string `json:"owner,omitempty"`
+	// Name of the client whose board holds the card.
+	OwnerName     string      `json:"ownerName,omitempty"`
+	ProjectID     string      `json:"projectId,omitempty"`
+	ProjectName   string      `json:"projectName,omitempty"`
+	ColumnID      string      `json:"columnId,omitempty"`
+	ColumnTitle   string      `json:"columnTitle,omitempty"`
+	ColumnPurpose string      `json:"columnPurpose,omitempty"`
+	Card          *KanbanCard `json:"card,omitempty"`
+}
+
+type CardChange struct {
+	Card *KanbanCard `json:"card,omitempty"`
+	// Column the card is in, or was last in for card.deleted.
+	ColumnID    string `json:"columnId,omitempty"`
+	ColumnTitle string `json:"columnTitle,omitempty"`
+	// Column a moved card left.
+	FromColumnID    string `json:"fromColumnId,omitempty"`
+	FromColumnTitle string `json:"fromColumnTitle,omitempty"`
+}
+
+type ChecklistItem struct {
+	ID        string `json:"id,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Completed bool   `json:"completed,omitempty"`
+}
+
+type ClientDeletedEvent struct {
+	// Handle of the deleted client.
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// One of cascade, transfer.
+	Mode string `json:"mode,omitempty"`
+	// Handle of the client that took over the data in transfer mode.
+	Target string `json:"target,omitempty"`
+}
+
+type ClientRecord struct {
+	ID            string `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	RecoveryCode  string `json:"recovery_code,omitempty"`
+	LastActive    int64  `json:"last_active,omitempty"`
+	IsAdmin       bool   `json:"is_admin,omitempty"`
+	DeletedAt     int64  `json:"deleted_at,omitempty"`
+	CascadeDelete bool   `json:"cascade_delete,omitempty"`
+}
+
+type ClientUpdate struct {
+	Name         string `json:"name"`
+	RecoveryCode string `json:"recovery_code"`
+	IsAdmin      bool   `json:"is_admin,omitempty"`
+}
+
+type DeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries,omitempty"`
+}
+
+type EmailPrefs struct {
+	Email string `json:"email,omitempty"`
+	// Cards due soon or overdue.
+	DueReminders bool `json:"due_reminders,omitempty"`
+	// Cards assigned to a project user linked to the caller.
+	Assignments bool `json:"assignments,omitempty"`
+	// Downloads of the caller's files through their share link.
+	ShareLinks bool `json:"share_links,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type FileList struct {
+	Files []FileRecord `json:"files,omitempty"`
+	Total int          `json:"total,omitempty"`
+}
+
+type FileRecord struct {
+	ID           string `json:"id,omitempty"`
+	OriginalName string `json:"original_name,omitempty"`
+	StoredPath   string `json:"stored_path,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	UploadTime   int64  `json:"upload_time,omitempty"`
+	OwnerID      string `json:"owner_id,omitempty"`
+	OwnerName    string `json:"owner_name,omitempty"`
+	DownloadLink string `json:"download_link,omitempty"`
+	IsPublic     bool   `json:"is_public,omitempty"`
+	DeletedAt    int64  `json:"deleted_at,omitempty"`
+	TrashedWith  string `json:"trashed_with,omitempty"`
+}
+
+type FileUpdate struct {
+	OriginalName string `json:"original_name"`
+	OwnerID      string `json:"owner_id"`
+	IsPublic     bool   `json:"is_public,omitempty"`
+}
+
+type InboundHook struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Column string `json:"column,omitempty"`
+	// Path to POST cards to.
+	Path string `json:"path,omitempty"`
+	// Only returned on create.
+	Token string `json:"token,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+	// Unix time in seconds; absent if never used.
+	LastUsedAt int64 `json:"last_used_at,omitempty"`
+}
+
+type InboundHookInput struct {
+	Name string `json:"name"`
+	// ID or title of the column cards go to when a request names none; the first column if empty.
+	Column string `json:"column,omitempty"`
+}
+
+type InboundHookList struct {
+	Hooks []InboundHook `json:"hooks,omitempty"`
+}
+
+type IntegrityIssue struct {
+	// One of orphan_blob, dangling_record, missing_owner, size_mismatch, unreadable_blob.
+	Kind     string `json:"kind,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	OwnerID  string `json:"owner_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+type IntegrityReport struct {
+	Records int              `json:"records,omitempty"`
+	Blobs   int              `json:"blobs,omitempty"`
+	Issues  []IntegrityIssue `json:"issues,omitempty"`
+	Repair  bool             `json:"repair,omitempty"`
+}
+
+type KanbanCard struct {
+	Version     string `json:"version,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+	ProjectID   string `json:"projectId,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+	DueDate     string `json:"dueDate,omitempty"`
+	// Unix time in milliseconds.
+	CreatedAt int64 `json:"createdAt,omitempty"`
+	// Nickname of a user of the card's project.
+	Assignee  string          `json:"assignee,omitempty"`
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+}
+
+type Manifest struct {
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// One of instance, persona.
+	Kind       string      `json:"kind,omitempty"`
+	AppVersion string      `json:"app_version,omitempty"`
+	CreatedAt  int64       `json:"created_at,omitempty"`
+	Personas   []string    `json:"personas,omitempty"`
+	Keys       int         `json:"keys,omitempty"`
+	Blobs      []BlobEntry `json:"blobs,omitempty"`
+}
+
+type MarkReadRequest struct {
+	// Notifications to mark; empty marks all.
+	Ids []string `json:"ids,omitempty"`
+}
+
+type MarkReadResponse struct {
+	Marked int `json:"marked,omitempty"`
+}
+
+// Client IDs are credentials: only the member's own project user carries its clientId.
+type Membership struct {
+	// Handle of the client owning the project, for /projects/{id}/board.
+	Owner string `json:"owner,omitempty"`
+	// Name of the client owning the project.
+	OwnerName string       `json:"ownerName,omitempty"`
+	Project   *Project     `json:"project,omitempty"`
+	User      *ProjectUser `json:"user,omitempty"`
+}
+
+type NameRequest struct {
+	Name string `json:"name"`
+}
+
+type NameResponse struct {
+	Status       string `json:"status,omitempty"`
+	ID           string `json:"id,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type NewCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// One of low, medium, high, urgent.
+	Priority string `json:"priority,omitempty"`
+	// ID or title of the column.
+	Column string `json:"column,omitempty"`
+}
+
+type Notification struct {
+	ID string `json:"id,omitempty"`
+	// One of card.due_soon, card.overdue, card.assigned, file.downloaded.
+	Type string `json:"type,omitempty"`
+	// Client whose board holds the card or who owns the file.
+	Owner     string `json:"owner,omitempty"`
+	CardID    string `json:"card_id,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	// Card title or file name when the notification was recorded.
+	Title   string `json:"title,omitempty"`
+	DueDate string `json:"due_date,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+	// Unix time in seconds; absent while unread.
+	ReadAt int64 `json:"read_at,omitempty"`
+}
+
+type NotificationList struct {
+	Notifications []Notification `json:"notifications,omitempty"`
+	// Unread notifications in total, also when only unread ones are listed.
+	Unread int `json:"unread,omitempty"`
+}
+
+type OutboxEntry struct {
+	ID       string `json:"id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	To       string `json:"to,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Html     string `json:"html,omitempty"`
+	// One of pending, failed.
+	Status   string `json:"status,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	// Unix time in seconds; zero once failed.
+	NextAttemptAt int64  `json:"next_attempt_at,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+type OutboxList struct {
+	Entries []OutboxEntry `json:"entries,omitempty"`
+}
+
+type Persona struct {
+	// One of client, admin.
+	Persona      string `json:"persona,omitempty"`
+	Name         string `json:"name,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+type Project struct {
+	Version string `json:"version,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	// Unique per client, without spaces.
+	Tag         string `json:"tag"`
+	Icon        string `json:"icon,omitempty"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Unix time in milliseconds.
+	CreatedAt int64         `json:"createdAt,omitempty"`
+	Users     []ProjectUser `json:"users,omitempty"`
+}
+
+type ProjectUser struct {
+	Version   string `json:"version,omitempty"`
+	ID        string `json:"id,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	// Unique within the project; cards name their assignee by it.
+	Nickname string `json:"nickname,omitempty"`
+	// Client account the user stands for; unique within the project. Linked clients see the project and the cards assigned to the user.
+	ClientID string `json:"clientId,omitempty"`
+}
+
+type Projects struct {
+	Version  string    `json:"version,omitempty"`
+	Projects []Project `json:"projects,omitempty"`
+}
+
+type PurgeResult struct {
+	Status string `json:"status,omitempty"`
+	Purged int    `json:"purged,omitempty"`
+}
+
+type RecoverRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoverResponse struct {
+	// One of client, admin.
+	Persona string `json:"persona,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results,omitempty"`
+}
+
+type SearchResult struct {
+	// One of card, project, file.
+	Kind      string  `json:"kind,omitempty"`
+	ID        string  `json:"id,omitempty"`
+	ProjectID string  `json:"project_id,omitempty"`
+	Title     string  `json:"title,omitempty"`
+	Score     float64 `json:"score,omitempty"`
+	// Matching fields by name, HTML-escaped with matches wrapped in <mark>; long fields are cut to a snippet.
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type Status struct {
+	Status string `json:"status"`
+}
+
+type Trash struct {
+	Files          []FileRecord   `json:"files,omitempty"`
+	Clients        []ClientRecord `json:"clients,omitempty"`
+	RetentionHours int64          `json:"retention_hours,omitempty"`
+}
+
+type Version struct {
+	Version string `json:"version,omitempty"`
+}
+
+type Webhook struct {
+	ID string `json:"id,omitempty"`
+	// Client whose events the webhook receives; absent for instance-wide webhooks, which receive every client's.
+	Owner    string   `json:"owner,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Secret   string   `json:"secret,omitempty"`
+	Events   []string `json:"events,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+type WebhookDelivery struct {
+	// Sent as X-Flow-Delivery.
+	ID        string `json:"id,omitempty"`
+	WebhookID string `json:"webhook_id,omitempty"`
+	EventID   string `json:"event_id,omitempty"`
+	Event     string `json:"event,omitempty"`
+	// The WebhookEvent as posted.
+	Payload map[string]any `json:"payload,omitempty"`
+	// One of pending, delivered, failed.
+	Status   string `json:"status,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	// Unix time in seconds; absent once finished.
+	NextAttemptAt int64 `json:"next_attempt_at,omitempty"`
+	// HTTP status of the last attempt; absent if there was no response.
+	ResponseStatus int    `json:"response_status,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+	// Unix time in seconds.
+	DeliveredAt int64 `json:"delivered_at,omitempty"`
+}
+
+// Body posted to webhooks. Card events carry a CardChange, file.uploaded a FileRecord without its stored_path and owner_id and client.deleted a ClientDeletedEvent.
+type WebhookEvent struct {
+	// Same for every webhook the event is delivered to.
+	ID string `json:"id,omitempty"`
+	// One of card.created, card.moved, card.deleted, file.uploaded, client.deleted.
+	Type string `json:"type,omitempty"`
+	// Handle of the client whose data the event is about; client IDs are never sent.
+	Owner string `json:"owner,omitempty"`
+	// Unix time in seconds.
+	CreatedAt int64          `json:"created_at,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+type WebhookInput struct {
+	// Absolute http or https URL.
+	URL string `json:"url"`
+	// Signing secret; generated on create and kept on update when empty.
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+	// Disabled webhooks get no deliveries.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+}
+
+// ExportBackup calls GET /admin/backup. Download a full instance backup (admin).
+func (c *Client) ExportBackup(ctx context.Context) (io.ReadCloser, error) {
+	return c.stream(ctx, "GET", "/admin/backup", nil, nil)
+}
+
+// CheckIntegrity calls GET /admin/fsck. Compare file records with stored blobs (admin).
+func (c *Client) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	var out IntegrityReport
+	if err := c.do(ctx, "GET", "/admin/fsck", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RepairIntegrity calls POST /admin/fsck. Check and repair file records and blobs (admin).
+func (c *Client) RepairIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	var out IntegrityReport
+	if err := c.do(ctx, "POST", "/admin/fsck", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListOutbox calls GET /admin/outbox. Emails waiting to be sent or given up on (admin).
+//
+// Sent emails are removed. A failed send is retried with an exponential backoff; after 8 attempts the email is marked failed and kept here.
+func (c *Client) ListOutbox(ctx context.Context) (*OutboxList, error) {
+	var out OutboxList
+	if err := c.do(ctx, "GET", "/admin/outbox", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreBackup calls POST /admin/restore. Replace all data with a backup (admin).
+func (c *Client) RestoreBackup(ctx context.Context, filename string, file io.Reader) (*ArchiveResult, error) {
+	var out ArchiveResult
+	if err := c.do(ctx, "POST", "/admin/restore", nil, filePayload("file", filename, file), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAllWebhooks calls GET /admin/webhooks. Webhooks of every client and instance-wide ones (admin).
+func (c *Client) ListAllWebhooks(ctx context.Context) (*WebhookList, error) {
+	var out WebhookList
+	if err := c.do(ctx, "GET", "/admin/webhooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateInstanceWebhook calls POST /admin/webhooks. Subscribe a URL to the events of every client (admin).
+func (c *Client) CreateInstanceWebhook(ctx context.Context, body WebhookInput) (*Webhook, error) {
+	var out Webhook
+	if err := c.do(ctx, "POST", "/admin/webhooks", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListClients calls GET /clients. All clients (admin).
+func (c *Client) ListClients(ctx context.Context) ([]ClientRecord, error) {
+	var out []ClientRecord
+	if err := c.do(ctx, "GET", "/clients", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateClient calls PUT /clients/{id}. Change name, recovery code or admin flag of a client (admin).
+func (c *Client) UpdateClient(ctx context.Context, id string, body ClientUpdate) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "PUT", "/clients/"+url.PathEscape(id), nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteClientParams are the query parameters of DeleteClient.
+type DeleteClientParams struct {
+	// One of cascade, transfer.
+	Mode   string
+	Target string
+}
+
+// DeleteClient calls DELETE /clients/{id}. Move a client to the trash (admin).
+//
+// mode=cascade trashes the client's files with it, mode=transfer hands all data to target first.
+func (c *Client) DeleteClient(ctx context.Context, id string, params *DeleteClientParams) (*Status, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Mode != "" {
+			q.Set("mode", params.Mode)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+	}
+	var out Status
+	if err := c.do(ctx, "DELETE", "/clients/"+url.PathEscape(id), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DownloadFile calls GET /download/{id}. File contents.
+func (c *Client) DownloadFile(ctx context.Context, id string) (io.ReadCloser, error) {
+	return c.stream(ctx, "GET", "/download/"+url.PathEscape(id), nil, nil)
+}
+
+// StreamEventsParams are the query parameters of StreamEvents.
+type StreamEventsParams struct {
+	// Client ID, when the X-Client-ID header cannot be sent
+	ClientID string
+}
+
+// StreamEvents calls GET /events. Live events of the caller as server-sent events.
+//
+// Sends a notification event with a Notification for every new notification and a notifications.read event with the MarkReadRequest when notifications are marked read, and a kanban.changed event with a CardChange when an inbound hook adds a card to the board. Idle streams get a comment every 25 seconds. EventSource cannot send headers, so the client ID may be given as client_id instead.
+func (c *Client) StreamEvents(ctx context.Context, params *StreamEventsParams) (io.ReadCloser, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.ClientID != "" {
+			q.Set("client_id", params.ClientID)
+		}
+	}
+	return c.stream(ctx, "GET", "/events", q, nil)
+}
+
+// ListFilesParams are the query parameters of ListFiles.
+type ListFilesParams struct {
+	Search string
+	Page   int
+	Limit  int
+}
+
+// ListFiles calls GET /files. Files of the caller, or of everyone for admins.
+func (c *Client) ListFiles(ctx context.Context, params *ListFilesParams) (*FileList, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Search != "" {
+			q.Set("search", params.Search)
+		}
+		if params.Page != 0 {
+			q.Set("page", strconv.Itoa(params.Page))
+		}
+		if params.Limit != 0 {
+			q.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out FileList
+	if err := c.do(ctx, "GET", "/files", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFile calls GET /files/{id}. Metadata of a file.
+func (c *Client) GetFile(ctx context.Context, id string) (*FileRecord, error) {
+	var out FileRecord
+	if err := c.do(ctx, "GET", "/files/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateFile calls PUT /files/{id}. Rename, hand over or publish a file.
+func (c *Client) UpdateFile(ctx context.Context, id string, body FileUpdate) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "PUT", "/files/"+url.PathEscape(id), nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteFile calls DELETE /files/{id}. Move a file to the trash.
+func (c *Client) DeleteFile(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "DELETE", "/files/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReceiveInboundHookParams are the query parameters of ReceiveInboundHook.
+type ReceiveInboundHookParams struct {
+	// Token of the inbound hook, for senders that cannot set headers
+	Token string
+}
+
+// ReceiveInboundHook calls POST /hooks/{id}. Add a card to the board of an inbound hook's owner.
+//
+// Authenticated by the hook's token instead of a client ID. An unknown hook and a wrong token both answer 404. The card goes to the named column, matched by ID and then by title, else to the hook's column, else to the first column.
+func (c *Client) ReceiveInboundHook(ctx context.Context, id string, params *ReceiveInboundHookParams, body NewCard) (*CardChange, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Token != "" {
+			q.Set("token", params.Token)
+		}
+	}
+	var out CardChange
+	if err := c.do(ctx, "POST", "/hooks/"+url.PathEscape(id), q, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListInboundHooks calls GET /inbound-hooks. Inbound hooks of the caller.
+func (c *Client) ListInboundHooks(ctx context.Context) (*InboundHookList, error) {
+	var out InboundHookList
+	if err := c.do(ctx, "GET", "/inbound-hooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateInboundHook calls POST /inbound-hooks. Create a URL that adds cards to the caller's board.
+//
+// The response is the only one that carries the hook's token; only a hash of it is stored.
+func (c *Client) CreateInboundHook(ctx context.Context, body InboundHookInput) (*InboundHook, error) {
+	var out InboundHook
+	if err := c.do(ctx, "POST", "/inbound-hooks", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteInboundHook calls DELETE /inbound-hooks/{id}. Revoke an inbound hook.
+func (c *Client) DeleteInboundHook(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "DELETE", "/inbound-hooks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetKanban calls GET /kanban. Kanban board of the caller.
+func (c *Client) GetKanban(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, "GET", "/kanban", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveKanban calls POST /kanban. Replace the kanban board of the caller.
+//
+// Cards must reference existing projects, and assignees must be nicknames of users of the card's project. References unchanged since the last save are not checked again. Violations are listed in the error details.
+func (c *Client) SaveKanban(ctx context.Context, body map[string]any) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "POST", "/kanban", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MyCardsParams are the query parameters of MyCards.
+type MyCardsParams struct {
+	// Nickname of the assignee, me for cards assigned to project users linked to the caller, or none for unassigned cards
+	Assignee string
+	// Comma-separated priorities
+	Priority string
+	// Cards due on or before this date
+	DueBefore string
+	// Cards due on or after this date
+	DueAfter string
+	// Project ID
+	Project string
+	// Comma-separated column purposes; none matches columns without one
+	Purpose string
+}
+
+// MyCards calls GET /me/cards. Cards of every board the caller can access, filtered.
+//
+// The caller's own board is searched as a whole; on other clients' boards only the cards of projects the caller is linked to. Cards due soonest come first; cards without a due date come last.
+func (c *Client) MyCards(ctx context.Context, params *MyCardsParams) ([]BoardCard, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Assignee != "" {
+			q.Set("assignee", params.Assignee)
+		}
+		if params.Priority != "" {
+			q.Set("priority", params.Priority)
+		}
+		if params.DueBefore != "" {
+			q.Set("due_before", params.DueBefore)
+		}
+		if params.DueAfter != "" {
+			q.Set("due_after", params.DueAfter)
+		}
+		if params.Project != "" {
+			q.Set("project", params.Project)
+		}
+		if params.Purpose != "" {
+			q.Set("purpose", params.Purpose)
+		}
+	}
+	var out []BoardCard
+	if err := c.do(ctx, "GET", "/me/cards", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListMemberships calls GET /me/projects. Projects of any client the caller is linked to.
+func (c *Client) ListMemberships(ctx context.Context) ([]Membership, error) {
+	var out []Membership
+	if err := c.do(ctx, "GET", "/me/projects", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MyWork calls GET /me/work. Cards assigned to the caller across all boards.
+//
+// Cards are assigned to the caller through project users linked to the caller's client ID. Cards due soonest come first.
+func (c *Client) MyWork(ctx context.Context) ([]BoardCard, error) {
+	var out []BoardCard
+	if err := c.do(ctx, "GET", "/me/work", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNotificationsParams are the query parameters of ListNotifications.
+type ListNotificationsParams struct {
+	// Only unread notifications
+	Unread bool
+}
+
+// ListNotifications calls GET /notifications. Notifications of the caller, newest first.
+//
+// The server records a notification when a card on the caller's board, or assigned to a project user linked to the caller, is due soon and again when it is overdue. Cards in columns with the purpose done are left out. A client linked to a project user also hears when a card is assigned to that user, and file owners when someone else downloads a file through its share link, at most once an hour per file. At most 200 notifications are kept.
+func (c *Client) ListNotifications(ctx context.Context, params *ListNotificationsParams) (*NotificationList, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Unread {
+			q.Set("unread", "true")
+		}
+	}
+	var out NotificationList
+	if err := c.do(ctx, "GET", "/notifications", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkNotificationsRead calls POST /notifications/read. Mark notifications read.
+func (c *Client) MarkNotificationsRead(ctx context.Context, body MarkReadRequest) (*MarkReadResponse, error) {
+	var out MarkReadResponse
+	if err := c.do(ctx, "POST", "/notifications/read", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPI calls GET /openapi.json. OpenAPI document of the API.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, "GET", "/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPersona calls GET /persona. Role, name and recovery code of the caller.
+func (c *Client) GetPersona(ctx context.Context) (*Persona, error) {
+	var out Persona
+	if err := c.do(ctx, "GET", "/persona", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ActivateAdmin calls POST /persona/admin. Make the caller an admin with the admin secret.
+func (c *Client) ActivateAdmin(ctx context.Context, body AdminRequest) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "POST", "/persona/admin", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetEmailPrefs calls GET /persona/email. Which notifications the caller gets by email.
+func (c *Client) GetEmailPrefs(ctx context.Context) (*EmailPrefs, error) {
+	var out EmailPrefs
+	if err := c.do(ctx, "GET", "/persona/email", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateEmailPrefs calls PUT /persona/email. Opt in to or out of notification emails.
+//
+// Every kind is off until enabled, and enabling any needs an email address. Emails are only sent when the instance has SMTP configured.
+func (c *Client) UpdateEmailPrefs(ctx context.Context, body EmailPrefs) (*EmailPrefs, error) {
+	var out EmailPrefs
+	if err := c.do(ctx, "PUT", "/persona/email", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportPersona calls GET /persona/export. Download all data of the caller as a takeout archive.
+func (c *Client) ExportPersona(ctx context.Context) (io.ReadCloser, error) {
+	return c.stream(ctx, "GET", "/persona/export", nil, nil)
+}
+
+// ImportPersonaParams are the query parameters of ImportPersona.
+type ImportPersonaParams struct {
+	// merge keeps existing data, replace drops it first
+	Mode string
+}
+
+// ImportPersona calls POST /persona/import. Load a takeout archive into the caller's data.
+func (c *Client) ImportPersona(ctx context.Context, params *ImportPersonaParams, filename string, file io.Reader) (*ArchiveResult, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Mode != "" {
+			q.Set("mode", params.Mode)
+		}
+	}
+	var out ArchiveResult
+	if err := c.do(ctx, "POST", "/persona/import", q, filePayload("file", filename, file), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetName calls POST /persona/name. Register the caller or change their name.
+//
+// The response carries the client ID derived from the recovery code; callers must use it from then on.
+func (c *Client) SetName(ctx context.Context, body NameRequest) (*NameResponse, error) {
+	var out NameResponse
+	if err := c.do(ctx, "POST", "/persona/name", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecoverPersona calls POST /persona/recover. Look up a client by recovery code.
+func (c *Client) RecoverPersona(ctx context.Context, body RecoverRequest) (*RecoverResponse, error) {
+	var out RecoverResponse
+	if err := c.do(ctx, "POST", "/persona/recover", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAPITokens calls GET /persona/tokens. API tokens of the caller.
+func (c *Client) ListAPITokens(ctx context.Context) (*APITokenList, error) {
+	var out APITokenList
+	if err := c.do(ctx, "GET", "/persona/tokens", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAPIToken calls POST /persona/tokens. Create a personal API token.
+//
+// The response is the only one that carries the token; only a hash of it is stored.
+func (c *Client) CreateAPIToken(ctx context.Context, body APITokenInput) (*APIToken, error) {
+	var out APIToken
+	if err := c.do(ctx, "POST", "/persona/tokens", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAPIToken calls DELETE /persona/tokens/{id}. Revoke an API token.
+func (c *Client) DeleteAPIToken(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "DELETE", "/persona/tokens/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProjects calls GET /projects. Projects of the caller.
+func (c *Client) ListProjects(ctx context.Context) (*Projects, error) {
+	var out Projects
+	if err := c.do(ctx, "GET", "/projects", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateProject calls POST /projects. Create a project.
+//
+// id and createdAt are generated unless given. Tags are unique per client, compared case-insensitively.
+func (c *Client) CreateProject(ctx context.Context, body Project) (*Project, error) {
+	var out Project
+	if err := c.do(ctx, "POST", "/projects", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProject calls GET /projects/{id}. A project of the caller.
+func (c *Client) GetProject(ctx context.Context, id string) (*Project, error) {
+	var out Project
+	if err := c.do(ctx, "GET", "/projects/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProjectParams are the query parameters of UpdateProject.
+type UpdateProjectParams struct {
+	// What happens to cards referencing removed projects or users: block (default) refuses with 409, clear drops the reference, reassign points it at target
+	Cascade string
+	// Project ID, or user ID of the same project, for cascade=reassign
+	Target string
+}
+
+// UpdateProject calls PUT /projects/{id}. Replace a project; id and createdAt are kept.
+//
+// Cards follow renamed nicknames. Users left out are removed as cascade says.
+func (c *Client) UpdateProject(ctx context.Context, id string, params *UpdateProjectParams, body Project) (*Project, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Cascade != "" {
+			q.Set("cascade", params.Cascade)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+	}
+	var out Project
+	if err := c.do(ctx, "PUT", "/projects/"+url.PathEscape(id), q, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteProjectParams are the query parameters of DeleteProject.
+type DeleteProjectParams struct {
+	// What happens to cards referencing removed projects or users: block (default) refuses with 409, clear drops the reference, reassign points it at target
+	Cascade string
+	// Project ID, or user ID of the same project, for cascade=reassign
+	Target string
+}
+
+// DeleteProject calls DELETE /projects/{id}. Delete a project.
+func (c *Client) DeleteProject(ctx context.Context, id string, params *DeleteProjectParams) (*Status, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Cascade != "" {
+			q.Set("cascade", params.Cascade)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+	}
+	var out Status
+	if err := c.do(ctx, "DELETE", "/projects/"+url.PathEscape(id), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProjectBoardParams are the query parameters of GetProjectBoard.
+type GetProjectBoardParams struct {
+	// Owner handle from /me/projects
+	Owner string
+}
+
+// GetProjectBoard calls GET /projects/{id}/board. Cards of one project on its owner's board.
+//
+// Every column of the owner's board is returned, holding only the cards of the project. Clients other than the owner must be linked to a user of the project; to them other projects do not exist. Without owner the caller's own project is used, else the first of their memberships with that project ID.
+func (c *Client) GetProjectBoard(ctx context.Context, id string, params *GetProjectBoardParams) (map[string]any, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Owner != "" {
+			q.Set("owner", params.Owner)
+		}
+	}
+	var out map[string]any
+	if err := c.do(ctx, "GET", "/projects/"+url.PathEscape(id)+"/board", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteProjectUserParams are the query parameters of DeleteProjectUser.
+type DeleteProjectUserParams struct {
+	// What happens to cards referencing removed projects or users: block (default) refuses with 409, clear drops the reference, reassign points it at target
+	Cascade string
+	// Project ID, or user ID of the same project, for cascade=reassign
+	Target string
+}
+
+// DeleteProjectUser calls DELETE /projects/{id}/users/{userId}. Remove a user from a project.
+func (c *Client) DeleteProjectUser(ctx context.Context, id string, userID string, params *DeleteProjectUserParams) (*Status, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Cascade != "" {
+			q.Set("cascade", params.Cascade)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+	}
+	var out Status
+	if err := c.do(ctx, "DELETE", "/projects/"+url.PathEscape(id)+"/users/"+url.PathEscape(userID), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchParams are the query parameters of Search.
+type SearchParams struct {
+	Q string
+	// Comma-separated kinds to search: card, project, file
+	Kind string
+	// Maximum number of results, default 20, at most 100
+	Limit int
+}
+
+// Search calls GET /search. Search cards, projects and files.
+//
+// Matches documents containing every word of q, as a whole word or word prefix. Card titles, descriptions and checklists, project names, tags and descriptions, and file names are searched. Results are ranked by how often and where the words occur, and how rare they are. The caller sees their own cards and projects, those of projects they are linked to, and their own and public files; admins see every file.
+func (c *Client) Search(ctx context.Context, params *SearchParams) (*SearchResponse, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Q != "" {
+			q.Set("q", params.Q)
+		}
+		if params.Kind != "" {
+			q.Set("kind", params.Kind)
+		}
+		if params.Limit != 0 {
+			q.Set("limit", strconv.Itoa(params.Limit))
+		}
+	}
+	var out SearchResponse
+	if err := c.do(ctx, "GET", "/search", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetValue calls GET /store/{key}. Value stored under key for the caller, null if unset.
+func (c *Client) GetValue(ctx context.Context, key string) (any, error) {
+	var out any
+	if err := c.do(ctx, "GET", "/store/"+url.PathEscape(key), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveValueParams are the query parameters of SaveValue.
+type SaveValueParams struct {
+	// What happens to cards referencing removed projects or users: block (default) refuses with 409, clear drops the reference, reassign points it at target
+	Cascade string
+	// Project ID, or user ID of the same project, for cascade=reassign
+	Target string
+}
+
+// SaveValue calls POST /store/{key}. Store a value under key for the caller.
+//
+// PROJECTS is validated like the projects endpoints and kanban like saveKanban. Keys the server manages (email_prefs, notifications, notifications_reminded and keys starting with file:, trash: or transferred:) are refused with 400.
+func (c *Client) SaveValue(ctx context.Context, key string, params *SaveValueParams, body any) (*Status, error) {
+	q := url.Values{}
+	if params != nil {
+		if params.Cascade != "" {
+			q.Set("cascade", params.Cascade)
+		}
+		if params.Target != "" {
+			q.Set("target", params.Target)
+		}
+	}
+	var out Status
+	if err := c.do(ctx, "POST", "/store/"+url.PathEscape(key), q, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTrash calls GET /trash. Trashed files of the caller; admins see all files and clients.
+func (c *Client) ListTrash(ctx context.Context) (*Trash, error) {
+	var out Trash
+	if err := c.do(ctx, "GET", "/trash", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EmptyTrash calls DELETE /trash. Permanently delete everything in the trash (admin).
+func (c *Client) EmptyTrash(ctx context.Context) (*PurgeResult, error) {
+	var out PurgeResult
+	if err := c.do(ctx, "DELETE", "/trash", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreClient calls POST /trash/clients/{id}/restore. Restore a trashed client and the files trashed with it (admin).
+func (c *Client) RestoreClient(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "POST", "/trash/clients/"+url.PathEscape(id)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreFile calls POST /trash/files/{id}/restore. Restore a trashed file.
+func (c *Client) RestoreFile(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "POST", "/trash/files/"+url.PathEscape(id)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadFile calls POST /upload. Upload a file owned by the caller.
+func (c *Client) UploadFile(ctx context.Context, filename string, file io.Reader) (*FileRecord, error) {
+	var out FileRecord
+	if err := c.do(ctx, "POST", "/upload", nil, filePayload("file", filename, file), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetVersion calls GET /version. Version of the running instance.
+func (c *Client) GetVersion(ctx context.Context) (*Version, error) {
+	var out Version
+	if err := c.do(ctx, "GET", "/version", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhooks calls GET /webhooks. Webhooks of the caller.
+func (c *Client) ListWebhooks(ctx context.Context) (*WebhookList, error) {
+	var out WebhookList
+	if err := c.do(ctx, "GET", "/webhooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWebhook calls POST /webhooks. Subscribe a URL to events about the caller's data.
+//
+// Webhook URLs of clients must resolve to public addresses and redirects are not followed; instance-wide webhooks may reach private addresses. Subscribers get a JSON POST with the WebhookEvent for every event they subscribe to, with the headers X-Flow-Event, X-Flow-Delivery and X-Flow-Signature. The signature is t=<unix seconds>,v1=<hex HMAC-SHA256>, keyed with the secret over the timestamp, a dot and the raw body. Anything but a 2xx answer is retried with an exponential backoff, up to 6 attempts.
+func (c *Client) CreateWebhook(ctx context.Context, body WebhookInput) (*Webhook, error) {
+	var out Webhook
+	if err := c.do(ctx, "POST", "/webhooks", nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWebhook calls GET /webhooks/{id}. A webhook of the caller, or any webhook for admins.
+func (c *Client) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var out Webhook
+	if err := c.do(ctx, "GET", "/webhooks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWebhook calls PUT /webhooks/{id}. Change the URL, events, secret or disabled flag of a webhook.
+func (c *Client) UpdateWebhook(ctx context.Context, id string, body WebhookInput) (*Webhook, error) {
+	var out Webhook
+	if err := c.do(ctx, "PUT", "/webhooks/"+url.PathEscape(id), nil, jsonPayload(body), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhook calls DELETE /webhooks/{id}. Remove a webhook with its delivery log.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, "DELETE", "/webhooks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookDeliveries calls GET /webhooks/{id}/deliveries. Delivery log of a webhook, newest first.
+//
+// Pending deliveries and the last 50 finished ones.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id string) (*DeliveryList, error) {
+	var out DeliveryList
+	if err := c.do(ctx, "GET", "/webhooks/"+url.PathEscape(id)+"/deliveries", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celerix-dev/celerix-flow/internal/api"
+	"github.com/celerix-dev/celerix-flow/internal/openapi"
+	"github.com/celerix-dev/celerix-flow/internal/search"
+	_ "github.com/celerix-dev/celerix-store/pkg/engine"
+	"github.com/celerix-dev/celerix-store/pkg/sdk"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestGeneratedClientIsCurrent(t *testing.T) {
+	doc, err := openapi.Parse(api.OpenAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := openapi.GenerateClient(doc, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("client_gen.go is out of date with openapi.json, run go generate ./pkg/client")
+	}
+}
+
+func TestClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// The store persists in the background, so the directory is removed
+	// without failing the test the way t.TempDir would
+	dir, err := os.MkdirTemp("", "flow-client-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := sdk.New(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageDir := filepath.Join(dir, "uploads")
+	os.MkdirAll(storageDir, 0755)
+	index := search.NewIndex()
+	h := &api.Handler{
+		Store:            search.Watch(store, index),
+		Index:            index,
+		StorageDir:       storageDir,
+		AdminSecret:      "test-secret",
+		VersionConfig:    []byte(`{"version": "1.0.0-test"}`),
+		CelerixNamespace: uuid.New(),
+	}
+	router := gin.New()
+	h.Mount(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL+"/api/v1/", "")
+
+	v, err := c.GetVersion(ctx)
+	if err != nil || v.Version != "1.0.0-test" {
+		t.Fatalf("GetVersion: %+v, %v", v, err)
+	}
+
+	// A new client registers with any ID and continues with the derived one
+	c.ClientID = uuid.NewString()
+	reg, err := c.SetName(ctx, NameRequest{Name: "Scripted"})
+	if err != nil {
+		t.Fatalf("SetName: %v", err)
+	}
+	c.ClientID = reg.ID
+
+	if _, err := c.ActivateAdmin(ctx, AdminRequest{Secret: "test-secret"}); err != nil {
+		t.Fatalf("ActivateAdmin: %v", err)
+	}
+	p, err := c.GetPersona(ctx)
+	if err != nil || p.Persona != "admin" || p.Name != "Scripted" {
+		t.Fatalf("GetPersona: %+v, %v", p, err)
+	}
+
+	rec, err := c.UploadFile(ctx, "notes.txt", bytes.NewBufferString("hello"))
+	if err != nil || rec.OriginalName != "notes.txt" || rec.Size != 5 {
+		t.Fatalf("UploadFile: %+v, %v", rec, err)
+	}
+	list, err := c.ListFiles(ctx, &ListFilesParams{Search: "notes", Limit: 10})
+	if err != nil || list.Total != 1 {
+		t.Fatalf("ListFiles: %+v, %v", list, err)
+	}
+
+	body, err := c.DownloadFile(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "hello" {
+		t.Errorf("expected downloaded contents, got %q", data)
+	}
+
+	if _, err := c.SaveValue(ctx, "settings", nil, map[string]any{"theme": "dark"}); err != nil {
+		t.Fatalf("SaveValue: %v", err)
+	}
+	val, err := c.GetValue(ctx, "settings")
+	if m, ok := val.(map[string]any); err != nil || !ok || m["theme"] != "dark" {
+		t.Fatalf("GetValue: %v, %v", val, err)
+	}
+
+	// Errors carry the decoded envelope
+	_, err = c.GetFile(ctx, "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "not_found" {
+		t.Fatalf("expected a not_found error, got %v", err)
+	}
+
+	// Scripts authenticate with an API token instead of the client ID
+	token, err := c.CreateAPIToken(ctx, APITokenInput{Name: "backup script", Scope: "read-only"})
+	if err != nil || token.Token == "" {
+		t.Fatalf("CreateAPIToken: %+v, %v", token, err)
+	}
+	script := New(srv.URL+"/api/v1", "")
+	script.Token = token.Token
+	if list, err := script.ListFiles(ctx, nil); err != nil || list.Total != 1 {
+		t.Fatalf("ListFiles with a token: %+v, %v", list, err)
+	}
+	_, err = script.UploadFile(ctx, "more.txt", bytes.NewBufferString("nope"))
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a read-only token to be refused an upload, got %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+package client
+
+//go:generate go run ../../cmd/clientgen -spec ../../internal/api/openapi.json -out client_gen.go -pkg client
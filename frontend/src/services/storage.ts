@@ -4,7 +4,7 @@ export const storageService = {
   async save<T>(key: string, data: T): Promise<void> {
     try {
       console.log(`Saving ${key} to Celerix Flow backend...`);
-      const path = key === 'KANBAN' ? '/api/kanban' : `/api/store/${key}`;
+      const path = key === 'KANBAN' ? '/api/v1/kanban' : `/api/v1/store/${key}`;
 
       if (path) {
         const response = await fetch(path, {
@@ -16,7 +16,8 @@ export const storageService = {
           body: JSON.stringify(data),
         });
         if (!response.ok) {
-           throw new Error(`Failed to save ${key}: ${response.statusText}`);
+           const body = await response.json().catch(() => null);
+           throw new Error(body?.error?.message || `Failed to save ${key}: ${response.statusText}`);
         }
       } else {
         console.warn(`Storage: key ${key} not explicitly handled by flow API yet`);
@@ -27,10 +28,24 @@ export const storageService = {
     }
   },
 
+  // Deletes a project; cards referencing it lose their project and assignee.
+  async deleteProject(id: string): Promise<void> {
+    const response = await fetch(`/api/v1/projects/${encodeURIComponent(id)}?cascade=clear`, {
+      method: 'DELETE',
+      headers: {
+        'X-Client-ID': getClientID(),
+      },
+    });
+    if (!response.ok) {
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.error?.message || `Failed to delete project: ${response.statusText}`);
+    }
+  },
+
   async load<T>(key: string): Promise<T | null> {
     try {
       console.log(`Loading ${key} from Celerix Flow backend...`);
-      const path = key === 'KANBAN' ? '/api/kanban' : `/api/store/${key}`;
+      const path = key === 'KANBAN' ? '/api/v1/kanban' : `/api/v1/store/${key}`;
 
       if (path) {
         const response = await fetch(path, {
@@ -27,7 +27,7 @@ export const clearAdminSecret = () => {
 
 export const activateAdmin = async (secret: string): Promise<{ success: boolean; error?: string }> => {
   try {
-    const response = await fetch('/api/persona/admin', {
+    const response = await fetch('/api/v1/persona/admin', {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -39,7 +39,7 @@ export const activateAdmin = async (secret: string): Promise<{ success: boolean;
       return { success: true };
     }
     const data = await response.json();
-    return { success: false, error: data.error };
+    return { success: false, error: data.error?.message };
   } catch (error) {
     console.error('Error activating admin:', error);
     return { success: false, error: 'Network error' };
@@ -55,7 +55,7 @@ export interface PersonaData {
 
 export const fetchPersona = async (): Promise<PersonaData> => {
   try {
-    const response = await fetch('/api/persona', {
+    const response = await fetch('/api/v1/persona', {
       headers: {
         'X-Client-ID': getClientID(),
         'X-Admin-Secret': getAdminSecret(),
@@ -72,7 +72,7 @@ export const fetchPersona = async (): Promise<PersonaData> => {
 
 export const updateClientName = async (name: string): Promise<{ success: boolean; id?: string; recovery_code?: string }> => {
   try {
-    const response = await fetch('/api/persona/name', {
+    const response = await fetch('/api/v1/persona/name', {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -97,7 +97,7 @@ export const updateClientName = async (name: string): Promise<{ success: boolean
 
 export const recoverPersona = async (code: string): Promise<{ success: boolean; persona?: string; name?: string }> => {
   try {
-    const response = await fetch('/api/persona/recover', {
+    const response = await fetch('/api/v1/persona/recover', {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -114,4 +114,111 @@ export const recoverPersona = async (code: string): Promise<{ success: boolean;
     console.error('Error recovering persona:', error);
     return { success: false };
   }
-};
\ No newline at end of file
+};
+export interface EmailPrefs {
+  email: string;
+  due_reminders: boolean;
+  assignments: boolean;
+  share_links: boolean;
+}
+
+export const fetchEmailPrefs = async (): Promise<EmailPrefs> => {
+  try {
+    const response = await fetch('/api/v1/persona/email', {
+      headers: {
+        'X-Client-ID': getClientID(),
+      },
+    });
+    if (response.ok) {
+      return await response.json();
+    }
+  } catch (error) {
+    console.error('Error fetching email preferences:', error);
+  }
+  return { email: '', due_reminders: false, assignments: false, share_links: false };
+};
+
+export const updateEmailPrefs = async (prefs: EmailPrefs): Promise<{ success: boolean; prefs?: EmailPrefs; error?: string }> => {
+  try {
+    const response = await fetch('/api/v1/persona/email', {
+      method: 'PUT',
+      headers: {
+        'Content-Type': 'application/json',
+        'X-Client-ID': getClientID(),
+      },
+      body: JSON.stringify(prefs),
+    });
+    const data = await response.json();
+    if (response.ok) {
+      return { success: true, prefs: data };
+    }
+    return { success: false, error: data.error?.message || 'Failed to save email preferences' };
+  } catch (error) {
+    console.error('Error updating email preferences:', error);
+    return { success: false, error: 'Failed to save email preferences' };
+  }
+};
+
+export type TokenScope = 'read-only' | 'read-write' | 'files-only';
+
+export interface APIToken {
+  id: string;
+  name: string;
+  scope: TokenScope;
+  token?: string;
+  created_at: number;
+  last_used_at?: number;
+}
+
+export const fetchAPITokens = async (): Promise<APIToken[]> => {
+  try {
+    const response = await fetch('/api/v1/persona/tokens', {
+      headers: {
+        'X-Client-ID': getClientID(),
+      },
+    });
+    if (response.ok) {
+      const data = await response.json();
+      return data.tokens || [];
+    }
+  } catch (error) {
+    console.error('Error fetching API tokens:', error);
+  }
+  return [];
+};
+
+export const createAPIToken = async (name: string, scope: TokenScope): Promise<{ success: boolean; token?: APIToken; error?: string }> => {
+  try {
+    const response = await fetch('/api/v1/persona/tokens', {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'X-Client-ID': getClientID(),
+      },
+      body: JSON.stringify({ name, scope }),
+    });
+    const data = await response.json();
+    if (response.ok) {
+      return { success: true, token: data };
+    }
+    return { success: false, error: data.error?.message || 'Failed to create API token' };
+  } catch (error) {
+    console.error('Error creating API token:', error);
+    return { success: false, error: 'Failed to create API token' };
+  }
+};
+
+export const revokeAPIToken = async (id: string): Promise<boolean> => {
+  try {
+    const response = await fetch(`/api/v1/persona/tokens/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+      headers: {
+        'X-Client-ID': getClientID(),
+      },
+    });
+    return response.ok;
+  } catch (error) {
+    console.error('Error revoking API token:', error);
+    return false;
+  }
+};